@@ -46,7 +46,6 @@ import (
 	"chain/net/http/reqid"
 	"chain/net/raft"
 	"chain/protocol"
-	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 )
 
@@ -365,9 +364,13 @@ func launchConfiguredCore(ctx context.Context, sdb *sinkdb.DB, db *sql.DB, conf
 		for _, signer := range remoteSignerInfo(ctx, processID, conf.BlockchainId.String(), conf, httpClient) {
 			signers = append(signers, signer)
 		}
-		c.MaxIssuanceWindow = bc.MillisDuration(conf.MaxIssuanceWindowMs)
+		config.SetGeneratorPolicy(c, conf)
 
 		gen := generator.New(c, signers, db)
+		err = gen.LoadFrozen(ctx)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
 		opts = append(opts, core.GeneratorLocal(gen))
 	} else {
 		opts = append(opts, core.GeneratorRemote(&rpc.Client{
@@ -27,6 +27,7 @@ import (
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/state"
+	"chain/protocol/vm"
 )
 
 const (
@@ -41,6 +42,8 @@ var (
 	ErrBadQuorum       = errors.New("quorum must be greater than 0 if there are signers")
 	ErrNoBlockPub      = errors.New("blockpub cannot be empty in mockhsm disabled build")
 	ErrNoBlockHSMURL   = errors.New("block hsm URL cannot be empty in mockhsm disabled build")
+	ErrBadOpcode       = errors.New("opcode is out of range")
+	ErrBadDenomination = errors.New("denomination is missing its asset ID")
 
 	Version, BuildCommit, BuildDate string
 
@@ -234,6 +237,11 @@ func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.C
 			return errors.Wrap(ErrBadQuorum)
 		}
 
+		err = checkGeneratorPolicy(c)
+		if err != nil {
+			return err
+		}
+
 		block, err := protocol.NewInitialBlock(signingKeys, int(c.Quorum), time.Now())
 		if err != nil {
 			return err
@@ -253,7 +261,7 @@ func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.C
 		}
 
 		c.BlockchainId = &initialBlockHash
-		chain.MaxIssuanceWindow = bc.MillisDuration(c.MaxIssuanceWindowMs)
+		SetGeneratorPolicy(chain, c)
 	}
 
 	b := make([]byte, 10)
@@ -270,6 +278,57 @@ func Configure(ctx context.Context, db pg.DB, sdb *sinkdb.DB, httpClient *http.C
 	)
 }
 
+// checkGeneratorPolicy checks that the generator policy in c can be
+// applied to a Chain.
+func checkGeneratorPolicy(c *Config) error {
+	for _, op := range c.AllowedOpcodes {
+		if op > 0xff {
+			return errors.WithDetailf(ErrBadOpcode, "allowed opcode %d", op)
+		}
+	}
+	for op := range c.DeprecatedOpcodes {
+		if op > 0xff {
+			return errors.WithDetailf(ErrBadOpcode, "deprecated opcode %d", op)
+		}
+	}
+	for i, d := range c.Denominations {
+		if d == nil || d.AssetId == nil {
+			return errors.WithDetailf(ErrBadDenomination, "denomination %d", i)
+		}
+	}
+	return nil
+}
+
+// SetGeneratorPolicy sets the fields of chain that are only used
+// by generators, such as MaxIssuanceWindow and AllowedOpcodes,
+// from the generator policy in c.
+func SetGeneratorPolicy(chain *protocol.Chain, c *Config) {
+	chain.MaxIssuanceWindow = bc.MillisDuration(c.MaxIssuanceWindowMs)
+	chain.AllowedOpcodes = nil
+	if len(c.AllowedOpcodes) > 0 {
+		chain.AllowedOpcodes = make(map[vm.Op]bool)
+		for _, op := range c.AllowedOpcodes {
+			chain.AllowedOpcodes[vm.Op(op)] = true
+		}
+	}
+	chain.DeprecatedOpcodes = nil
+	if len(c.DeprecatedOpcodes) > 0 {
+		chain.DeprecatedOpcodes = make(map[vm.Op]uint64)
+		for op, height := range c.DeprecatedOpcodes {
+			chain.DeprecatedOpcodes[vm.Op(op)] = height
+		}
+	}
+	chain.Denominations = nil
+	if len(c.Denominations) > 0 {
+		chain.Denominations = make(map[bc.AssetID]uint64)
+		for _, d := range c.Denominations {
+			chain.Denominations[*d.AssetId] = d.Unit
+		}
+	}
+	chain.MaxWitnessSize = int(c.MaxWitnessSize)
+	chain.MaxTxRunCost = c.MaxTxRunCost
+}
+
 func tryGenerator(ctx context.Context, url, accessToken, blockchainID string, httpClient *http.Client) error {
 	client := &rpc.Client{
 		BaseURL:      url,
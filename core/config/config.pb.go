@@ -11,6 +11,7 @@ It is generated from these files:
 It has these top-level messages:
 	Config
 	BlockSigner
+	Denomination
 */
 package config
 
@@ -31,19 +32,24 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Config struct {
-	Id                   string         `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	IsSigner             bool           `protobuf:"varint,2,opt,name=is_signer,json=isSigner" json:"is_signer,omitempty"`
-	IsGenerator          bool           `protobuf:"varint,3,opt,name=is_generator,json=isGenerator" json:"is_generator,omitempty"`
-	BlockchainId         *bc.Hash       `protobuf:"bytes,4,opt,name=blockchain_id,json=blockchainId" json:"blockchain_id,omitempty"`
-	GeneratorUrl         string         `protobuf:"bytes,5,opt,name=generator_url,json=generatorUrl" json:"generator_url,omitempty"`
-	GeneratorAccessToken string         `protobuf:"bytes,6,opt,name=generator_access_token,json=generatorAccessToken" json:"generator_access_token,omitempty"`
-	BlockHsmUrl          string         `protobuf:"bytes,7,opt,name=block_hsm_url,json=blockHsmUrl" json:"block_hsm_url,omitempty"`
-	BlockHsmAccessToken  string         `protobuf:"bytes,8,opt,name=block_hsm_access_token,json=blockHsmAccessToken" json:"block_hsm_access_token,omitempty"`
-	ConfiguredAt         uint64         `protobuf:"varint,9,opt,name=configured_at,json=configuredAt" json:"configured_at,omitempty"`
-	BlockPub             []byte         `protobuf:"bytes,10,opt,name=block_pub,json=blockPub,proto3" json:"block_pub,omitempty"`
-	Signers              []*BlockSigner `protobuf:"bytes,11,rep,name=signers" json:"signers,omitempty"`
-	Quorum               uint32         `protobuf:"varint,12,opt,name=quorum" json:"quorum,omitempty"`
-	MaxIssuanceWindowMs  uint64         `protobuf:"varint,13,opt,name=max_issuance_window_ms,json=maxIssuanceWindowMs" json:"max_issuance_window_ms,omitempty"`
+	Id                   string            `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	IsSigner             bool              `protobuf:"varint,2,opt,name=is_signer,json=isSigner" json:"is_signer,omitempty"`
+	IsGenerator          bool              `protobuf:"varint,3,opt,name=is_generator,json=isGenerator" json:"is_generator,omitempty"`
+	BlockchainId         *bc.Hash          `protobuf:"bytes,4,opt,name=blockchain_id,json=blockchainId" json:"blockchain_id,omitempty"`
+	GeneratorUrl         string            `protobuf:"bytes,5,opt,name=generator_url,json=generatorUrl" json:"generator_url,omitempty"`
+	GeneratorAccessToken string            `protobuf:"bytes,6,opt,name=generator_access_token,json=generatorAccessToken" json:"generator_access_token,omitempty"`
+	BlockHsmUrl          string            `protobuf:"bytes,7,opt,name=block_hsm_url,json=blockHsmUrl" json:"block_hsm_url,omitempty"`
+	BlockHsmAccessToken  string            `protobuf:"bytes,8,opt,name=block_hsm_access_token,json=blockHsmAccessToken" json:"block_hsm_access_token,omitempty"`
+	ConfiguredAt         uint64            `protobuf:"varint,9,opt,name=configured_at,json=configuredAt" json:"configured_at,omitempty"`
+	BlockPub             []byte            `protobuf:"bytes,10,opt,name=block_pub,json=blockPub,proto3" json:"block_pub,omitempty"`
+	Signers              []*BlockSigner    `protobuf:"bytes,11,rep,name=signers" json:"signers,omitempty"`
+	Quorum               uint32            `protobuf:"varint,12,opt,name=quorum" json:"quorum,omitempty"`
+	MaxIssuanceWindowMs  uint64            `protobuf:"varint,13,opt,name=max_issuance_window_ms,json=maxIssuanceWindowMs" json:"max_issuance_window_ms,omitempty"`
+	AllowedOpcodes       []uint32          `protobuf:"varint,14,rep,name=allowed_opcodes,json=allowedOpcodes" json:"allowed_opcodes,omitempty"`
+	DeprecatedOpcodes    map[uint32]uint64 `protobuf:"bytes,15,rep,name=deprecated_opcodes,json=deprecatedOpcodes" json:"deprecated_opcodes,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Denominations        []*Denomination   `protobuf:"bytes,16,rep,name=denominations" json:"denominations,omitempty"`
+	MaxWitnessSize       uint64            `protobuf:"varint,17,opt,name=max_witness_size,json=maxWitnessSize" json:"max_witness_size,omitempty"`
+	MaxTxRunCost         int64             `protobuf:"varint,18,opt,name=max_tx_run_cost,json=maxTxRunCost" json:"max_tx_run_cost,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -142,6 +148,41 @@ func (m *Config) GetMaxIssuanceWindowMs() uint64 {
 	return 0
 }
 
+func (m *Config) GetAllowedOpcodes() []uint32 {
+	if m != nil {
+		return m.AllowedOpcodes
+	}
+	return nil
+}
+
+func (m *Config) GetDeprecatedOpcodes() map[uint32]uint64 {
+	if m != nil {
+		return m.DeprecatedOpcodes
+	}
+	return nil
+}
+
+func (m *Config) GetDenominations() []*Denomination {
+	if m != nil {
+		return m.Denominations
+	}
+	return nil
+}
+
+func (m *Config) GetMaxWitnessSize() uint64 {
+	if m != nil {
+		return m.MaxWitnessSize
+	}
+	return 0
+}
+
+func (m *Config) GetMaxTxRunCost() int64 {
+	if m != nil {
+		return m.MaxTxRunCost
+	}
+	return 0
+}
+
 type BlockSigner struct {
 	AccessToken string `protobuf:"bytes,1,opt,name=access_token,json=accessToken" json:"access_token,omitempty"`
 	Pubkey      []byte `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
@@ -174,39 +215,76 @@ func (m *BlockSigner) GetUrl() string {
 	return ""
 }
 
+type Denomination struct {
+	AssetId *bc.AssetID `protobuf:"bytes,1,opt,name=asset_id,json=assetId" json:"asset_id,omitempty"`
+	Unit    uint64      `protobuf:"varint,2,opt,name=unit" json:"unit,omitempty"`
+}
+
+func (m *Denomination) Reset()                    { *m = Denomination{} }
+func (m *Denomination) String() string            { return proto.CompactTextString(m) }
+func (*Denomination) ProtoMessage()               {}
+func (*Denomination) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (m *Denomination) GetAssetId() *bc.AssetID {
+	if m != nil {
+		return m.AssetId
+	}
+	return nil
+}
+
+func (m *Denomination) GetUnit() uint64 {
+	if m != nil {
+		return m.Unit
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Config)(nil), "config.Config")
 	proto.RegisterType((*BlockSigner)(nil), "config.BlockSigner")
+	proto.RegisterType((*Denomination)(nil), "config.Denomination")
 }
 
 func init() { proto.RegisterFile("config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 407 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x92, 0x4f, 0x6f, 0xd4, 0x30,
-	0x10, 0xc5, 0x95, 0x4d, 0x49, 0xb3, 0x93, 0x04, 0x21, 0x2f, 0x5a, 0x59, 0xe5, 0x12, 0xb6, 0x97,
-	0x5c, 0xba, 0x2b, 0xb5, 0x7c, 0x81, 0xc2, 0x81, 0xf6, 0x80, 0x84, 0x02, 0x08, 0x89, 0x8b, 0x65,
-	0x3b, 0x66, 0xd7, 0xda, 0x4d, 0xbc, 0xd8, 0xb1, 0x5a, 0x3e, 0x3a, 0x37, 0xe4, 0x49, 0xf6, 0x4f,
-	0x6f, 0x99, 0xf7, 0x7e, 0x79, 0x1e, 0x8f, 0x07, 0x72, 0x69, 0xba, 0xdf, 0x7a, 0xbd, 0xdc, 0x5b,
-	0xd3, 0x1b, 0x92, 0x0c, 0xd5, 0xd5, 0x95, 0xdc, 0x70, 0xdd, 0xad, 0x50, 0x94, 0x66, 0xb7, 0x12,
-	0x72, 0x25, 0xe4, 0xc0, 0x2c, 0xfe, 0xc5, 0x90, 0x7c, 0x42, 0x8c, 0xbc, 0x86, 0x89, 0x6e, 0x68,
-	0x54, 0x46, 0xd5, 0xb4, 0x9e, 0xe8, 0x86, 0xbc, 0x83, 0xa9, 0x76, 0xcc, 0xe9, 0x75, 0xa7, 0x2c,
-	0x9d, 0x94, 0x51, 0x95, 0xd6, 0xa9, 0x76, 0xdf, 0xb0, 0x26, 0xef, 0x21, 0xd7, 0x8e, 0xad, 0x55,
-	0xa7, 0x2c, 0xef, 0x8d, 0xa5, 0x31, 0xfa, 0x99, 0x76, 0x9f, 0x0f, 0x12, 0xb9, 0x81, 0x42, 0xec,
-	0x8c, 0xdc, 0xe2, 0xe9, 0x4c, 0x37, 0xf4, 0xa2, 0x8c, 0xaa, 0xec, 0x36, 0x5d, 0x0a, 0xb9, 0x7c,
-	0xe0, 0x6e, 0x53, 0xe7, 0x27, 0xfb, 0xb1, 0x21, 0xd7, 0x50, 0x1c, 0xe3, 0x98, 0xb7, 0x3b, 0xfa,
-	0x0a, 0x3b, 0xc9, 0x8f, 0xe2, 0x0f, 0xbb, 0x23, 0x1f, 0x60, 0x7e, 0x82, 0xb8, 0x94, 0xca, 0x39,
-	0xd6, 0x9b, 0xad, 0xea, 0x68, 0x82, 0xf4, 0xdb, 0xa3, 0x7b, 0x8f, 0xe6, 0xf7, 0xe0, 0x91, 0xc5,
-	0xd8, 0x09, 0xdb, 0xb8, 0x16, 0xa3, 0x2f, 0x11, 0xce, 0x50, 0x7c, 0x70, 0x6d, 0x48, 0xbe, 0x83,
-	0xf9, 0x89, 0x79, 0x91, 0x9c, 0x22, 0x3c, 0x3b, 0xc0, 0xe7, 0xc1, 0xd7, 0x50, 0x0c, 0x33, 0xf6,
-	0x56, 0x35, 0x8c, 0xf7, 0x74, 0x5a, 0x46, 0xd5, 0x45, 0x9d, 0x9f, 0xc4, 0xfb, 0x3e, 0xcc, 0x71,
-	0x48, 0xde, 0x7b, 0x41, 0xa1, 0x8c, 0xaa, 0xbc, 0x4e, 0x51, 0xf8, 0xea, 0x05, 0xb9, 0x81, 0xcb,
-	0x61, 0xc2, 0x8e, 0x66, 0x65, 0x5c, 0x65, 0xb7, 0xb3, 0xe5, 0xf8, 0x86, 0x1f, 0x03, 0x32, 0x4c,
-	0xbb, 0x3e, 0x30, 0x64, 0x0e, 0xc9, 0x1f, 0x6f, 0xac, 0x6f, 0x69, 0x5e, 0x46, 0x55, 0x51, 0x8f,
-	0x55, 0xe8, 0xbe, 0xe5, 0xcf, 0x4c, 0x3b, 0xe7, 0x79, 0x27, 0x15, 0x7b, 0xd2, 0x5d, 0x63, 0x9e,
-	0x58, 0xeb, 0x68, 0x81, 0x1d, 0xcd, 0x5a, 0xfe, 0xfc, 0x38, 0x9a, 0x3f, 0xd1, 0xfb, 0xe2, 0x16,
-	0xbf, 0x20, 0x3b, 0x3b, 0x24, 0x3c, 0xe9, 0x8b, 0x7b, 0x0f, 0x9b, 0x90, 0xf1, 0xb3, 0xfb, 0xce,
-	0x21, 0xd9, 0x7b, 0xb1, 0x55, 0x7f, 0x71, 0x1f, 0xf2, 0x7a, 0xac, 0xc8, 0x1b, 0x88, 0xc3, 0x58,
-	0x63, 0xfc, 0x23, 0x7c, 0x8a, 0x04, 0xd7, 0xeb, 0xee, 0x7f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xe7,
-	0xf2, 0xbf, 0xbe, 0x92, 0x02, 0x00, 0x00,
+	// 612 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0xdf, 0x4f, 0xdb, 0x3c,
+	0x14, 0x55, 0xda, 0x12, 0xca, 0x4d, 0x52, 0xc0, 0xa0, 0xca, 0xe2, 0x7b, 0xc9, 0x57, 0xc4, 0xf7,
+	0xe5, 0x85, 0x22, 0xc1, 0x1e, 0x26, 0xde, 0x18, 0x9d, 0x46, 0x27, 0x4d, 0x9b, 0x0c, 0x13, 0xd2,
+	0x5e, 0x2c, 0x27, 0xf1, 0xc0, 0x22, 0xb1, 0xbb, 0xd8, 0x59, 0x0b, 0xff, 0xe2, 0xfe, 0xa9, 0xc9,
+	0x4e, 0xfa, 0x83, 0x69, 0x6f, 0xbe, 0xe7, 0x9c, 0x1c, 0x9f, 0x5c, 0xdf, 0x0b, 0x61, 0xa6, 0xe4,
+	0x77, 0xf1, 0x30, 0x9e, 0x55, 0xca, 0x28, 0xe4, 0x37, 0xd5, 0xd1, 0x51, 0xf6, 0xc8, 0x84, 0x3c,
+	0x73, 0x60, 0xa6, 0x8a, 0xb3, 0x34, 0x3b, 0x4b, 0xb3, 0x46, 0x33, 0xfa, 0xe5, 0x83, 0x7f, 0xed,
+	0x64, 0x68, 0x00, 0x1d, 0x91, 0x63, 0x2f, 0xf6, 0x92, 0x1d, 0xd2, 0x11, 0x39, 0xfa, 0x07, 0x76,
+	0x84, 0xa6, 0x5a, 0x3c, 0x48, 0x5e, 0xe1, 0x4e, 0xec, 0x25, 0x7d, 0xd2, 0x17, 0xfa, 0xd6, 0xd5,
+	0xe8, 0x5f, 0x08, 0x85, 0xa6, 0x0f, 0x5c, 0xf2, 0x8a, 0x19, 0x55, 0xe1, 0xae, 0xe3, 0x03, 0xa1,
+	0x3f, 0x2c, 0x21, 0x74, 0x0a, 0x51, 0x5a, 0xa8, 0xec, 0xc9, 0xdd, 0x4e, 0x45, 0x8e, 0x7b, 0xb1,
+	0x97, 0x04, 0xe7, 0xfd, 0x71, 0x9a, 0x8d, 0x6f, 0x98, 0x7e, 0x24, 0xe1, 0x9a, 0x9e, 0xe6, 0xe8,
+	0x18, 0xa2, 0x95, 0x1d, 0xad, 0xab, 0x02, 0x6f, 0xb9, 0x24, 0xe1, 0x0a, 0xfc, 0x5a, 0x15, 0xe8,
+	0x0d, 0x0c, 0xd7, 0x22, 0x96, 0x65, 0x5c, 0x6b, 0x6a, 0xd4, 0x13, 0x97, 0xd8, 0x77, 0xea, 0xc3,
+	0x15, 0x7b, 0xe5, 0xc8, 0x3b, 0xcb, 0xa1, 0x51, 0x9b, 0x84, 0x3e, 0xea, 0xd2, 0x59, 0x6f, 0x3b,
+	0x71, 0xe0, 0xc0, 0x1b, 0x5d, 0x5a, 0xe7, 0x0b, 0x18, 0xae, 0x35, 0xaf, 0x9c, 0xfb, 0x4e, 0x7c,
+	0xb0, 0x14, 0x6f, 0x1a, 0x1f, 0x43, 0xd4, 0xf4, 0xb8, 0xae, 0x78, 0x4e, 0x99, 0xc1, 0x3b, 0xb1,
+	0x97, 0xf4, 0x48, 0xb8, 0x06, 0xaf, 0x8c, 0xed, 0x63, 0xe3, 0x3c, 0xab, 0x53, 0x0c, 0xb1, 0x97,
+	0x84, 0xa4, 0xef, 0x80, 0x2f, 0x75, 0x8a, 0x4e, 0x61, 0xbb, 0xe9, 0xb0, 0xc6, 0x41, 0xdc, 0x4d,
+	0x82, 0xf3, 0x83, 0x71, 0xfb, 0x86, 0xef, 0xac, 0xa4, 0xe9, 0x36, 0x59, 0x6a, 0xd0, 0x10, 0xfc,
+	0x1f, 0xb5, 0xaa, 0xea, 0x12, 0x87, 0xb1, 0x97, 0x44, 0xa4, 0xad, 0x6c, 0xfa, 0x92, 0x2d, 0xa8,
+	0xd0, 0xba, 0x66, 0x32, 0xe3, 0x74, 0x2e, 0x64, 0xae, 0xe6, 0xb4, 0xd4, 0x38, 0x72, 0x89, 0x0e,
+	0x4a, 0xb6, 0x98, 0xb6, 0xe4, 0xbd, 0xe3, 0x3e, 0x69, 0xf4, 0x3f, 0xec, 0xb2, 0xa2, 0x50, 0x73,
+	0x9e, 0x53, 0x35, 0xcb, 0x54, 0xce, 0x35, 0x1e, 0xc4, 0xdd, 0x24, 0x22, 0x83, 0x16, 0xfe, 0xdc,
+	0xa0, 0xe8, 0x0e, 0x50, 0xce, 0x67, 0x15, 0xcf, 0x98, 0xd9, 0xd0, 0xee, 0xba, 0xbc, 0x27, 0xcb,
+	0xbc, 0xcd, 0x14, 0x8d, 0x27, 0x2b, 0x61, 0xfb, 0xf5, 0x7b, 0x69, 0xaa, 0x67, 0xb2, 0x9f, 0xff,
+	0x89, 0xa3, 0x4b, 0x88, 0x72, 0x2e, 0x55, 0x29, 0x24, 0x33, 0x42, 0x49, 0x8d, 0xf7, 0x9c, 0xe1,
+	0xe1, 0xd2, 0x70, 0xb2, 0x41, 0x92, 0xd7, 0x52, 0x94, 0xc0, 0x9e, 0xfd, 0xdf, 0xb9, 0x30, 0xd2,
+	0x3e, 0x94, 0x16, 0x2f, 0x1c, 0xef, 0xbb, 0x3f, 0x1d, 0x94, 0x6c, 0x71, 0xdf, 0xc0, 0xb7, 0xe2,
+	0x85, 0xa3, 0x13, 0xd8, 0xb5, 0x4a, 0xb3, 0xa0, 0x55, 0x2d, 0x69, 0xa6, 0xb4, 0xc1, 0x28, 0xf6,
+	0x92, 0x2e, 0x09, 0x4b, 0xb6, 0xb8, 0x5b, 0x90, 0x5a, 0x5e, 0x2b, 0x6d, 0x8e, 0x26, 0x30, 0xfc,
+	0x7b, 0x72, 0xb4, 0x07, 0xdd, 0x27, 0xfe, 0xec, 0xf6, 0x22, 0x22, 0xf6, 0x88, 0x0e, 0x61, 0xeb,
+	0x27, 0x2b, 0x6a, 0xee, 0x96, 0xa2, 0x47, 0x9a, 0xe2, 0xb2, 0xf3, 0xd6, 0x1b, 0x7d, 0x83, 0x60,
+	0xe3, 0xd9, 0xec, 0x92, 0xbc, 0x9a, 0xa4, 0x66, 0xb7, 0x02, 0xb6, 0x31, 0x41, 0x43, 0xf0, 0x67,
+	0x75, 0x6a, 0x2f, 0xe8, 0xb8, 0xc9, 0x68, 0x2b, 0x7b, 0xab, 0x1d, 0xd4, 0xae, 0xfb, 0xc2, 0x1e,
+	0x47, 0x1f, 0x21, 0xdc, 0xec, 0x08, 0xfa, 0x0f, 0xfa, 0x4c, 0x6b, 0x6e, 0x68, 0xbb, 0xb4, 0xc1,
+	0x79, 0x60, 0x37, 0xeb, 0xca, 0x62, 0xd3, 0x09, 0xd9, 0x76, 0xe4, 0x34, 0x47, 0x08, 0x7a, 0xb5,
+	0x14, 0xa6, 0x0d, 0xeb, 0xce, 0xa9, 0xef, 0x96, 0xff, 0xe2, 0xf7, 0x00, 0x89, 0xd8, 0xa0, 0x3e,
+	0x30, 0x04, 0x00, 0x00,
 }
@@ -8,6 +8,7 @@ import (
 	"chain/core/asset"
 	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/generator"
 	"chain/core/leader"
 	"chain/core/query"
 	"chain/core/query/filter"
@@ -31,6 +32,8 @@ func isTemporary(info httperror.Info, err error) bool {
 		return true
 	case "CH001": // request timed out
 		return true
+	case "CH741": // generator paused
+		return true
 	case "CH761": // outputs currently reserved
 		return true
 	case "CH706": // 1 or more action errors
@@ -86,6 +89,8 @@ var errorFormatter = httperror.Formatter{
 		errNoMockHSM:                   {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		errNoReset:                     {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		config.ErrNoBlockHSMURL:        {400, "CH111", "Block HSM URL cannot be empty when configuring a non mockhsm signer"},
+		config.ErrBadOpcode:            {400, "CH112", "Generator policy names an opcode that is out of range"},
+		config.ErrBadDenomination:      {400, "CH113", "Generator policy denomination is missing its asset ID"},
 		errNoClientTokens:              {400, "CH120", "Cannot enable client authentication with no client tokens"},
 		blocksigner.ErrConsensusChange: {400, "CH150", "Refuse to sign block with consensus change"},
 		errMissingAddr:                 {400, "CH160", "Address is missing"},
@@ -136,6 +141,9 @@ var errorFormatter = httperror.Formatter{
 		txbuilder.ErrNoTxSighashCommitment: {400, "CH736", "Transaction is not final, additional actions still allowed"},
 		txbuilder.ErrTxSignatureFailure:    {400, "CH737", "Transaction signature missing, client may be missing signature key"},
 		txbuilder.ErrNoTxSighashAttempt:    {400, "CH738", "Transaction signature was not attempted"},
+		generator.ErrOutputFrozen:          {400, "CH739", "Transaction spends a frozen output"},
+		generator.ErrBadRefData:            {400, "CH740", "Transaction reference data was rejected by the generator"},
+		generator.ErrPaused:                {503, "CH741", "Generator is paused; try again soon"},
 
 		// account action error namespace (76x)
 		account.ErrInsufficient: {400, "CH760", "Insufficient funds for tx"},
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"chain/core/generator"
 	"chain/database/pg"
 	"chain/errors"
 )
@@ -21,6 +22,7 @@ func TestErrorMapping(t *testing.T) {
 		{errors.Wrap(pg.ErrUserInputNotFound, "foo"), `{"code":"CH002","message":"Not found","temporary":false}`, 400},
 		{errors.WithDetail(pg.ErrUserInputNotFound, "foo"), `{"code":"CH002","message":"Not found","detail":"foo","temporary":false}`, 400},
 		{context.DeadlineExceeded, `{"code":"CH001","message":"Request timed out","temporary":true}`, 408},
+		{errors.Wrap(generator.ErrPaused), `{"code":"CH741","message":"Generator is paused; try again soon","temporary":true}`, 503},
 	}
 
 	for _, test := range cases {
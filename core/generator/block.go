@@ -65,6 +65,9 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 		}
 	} else {
 		g.mu.Lock()
+		if latestBlock != nil {
+			g.removeFrozen(latestBlock.Height + 1)
+		}
 		txs := g.pool
 		g.pool = nil
 		g.poolHashes = make(map[bc.Hash]bool)
@@ -75,7 +78,13 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 			return errors.Wrap(err, "generate")
 		}
 		if len(b.Transactions) == 0 {
-			return nil // don't bother making an empty block
+			switch g.EmptyBlocks {
+			case MakeEmptyBlocks:
+			case FailEmptyBlocks:
+				return ErrNoPendingTxs
+			default:
+				return nil // don't bother making an empty block
+			}
 		}
 		err = savePendingBlock(ctx, g.db, b)
 		if err != nil {
@@ -6,16 +6,66 @@ package generator
 
 import (
 	"context"
+	"io/ioutil"
 	"sync"
 	"time"
 
 	"chain/database/pg"
+	"chain/errors"
 	"chain/log"
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 )
 
+var (
+	// ErrTxNotPending is returned when an operation requires a
+	// transaction to be in the pending tx pool and it is not.
+	ErrTxNotPending = errors.New("transaction not pending")
+
+	// ErrOutputFrozen is returned when a submitted transaction
+	// spends an output frozen with FreezeOutput or ScheduleFreeze.
+	ErrOutputFrozen = errors.New("transaction spends a frozen output")
+
+	// ErrBlockFull is returned by CheckMineable when the txs ahead
+	// of a pending tx would fill the next block without it.
+	ErrBlockFull = errors.New("next block is full")
+
+	// ErrBadRefData is returned when a submitted transaction's
+	// reference data is rejected by the RefDataValidator.
+	ErrBadRefData = errors.New("invalid reference data")
+
+	// ErrNoPendingTxs is returned by block generation under
+	// FailEmptyBlocks when there are no pending txs to include.
+	ErrNoPendingTxs = errors.New("no pending transactions")
+
+	// ErrPaused is returned when a transaction is submitted while
+	// the generator is paused.
+	ErrPaused = errors.New("generator paused")
+
+	// ErrPoolInconsistent is reported by AuditPool for each
+	// inconsistency it finds in the pending tx pool.
+	ErrPoolInconsistent = errors.New("pending tx pool inconsistent")
+)
+
+// EmptyBlockPolicy says what the generator does at the end of a
+// block period in which no pending tx can be included in a block.
+type EmptyBlockPolicy int
+
+const (
+	// SkipEmptyBlocks makes no block for the period. It is the
+	// default.
+	SkipEmptyBlocks EmptyBlockPolicy = iota
+
+	// MakeEmptyBlocks makes a block with no transactions, so that
+	// blocks arrive every period and can serve as a heartbeat.
+	MakeEmptyBlocks
+
+	// FailEmptyBlocks makes no block and reports ErrNoPendingTxs
+	// to the health function passed to Generate.
+	FailEmptyBlocks
+)
+
 // A BlockSigner signs blocks.
 type BlockSigner interface {
 	// SignBlock returns an ed25519 signature over the block's sighash.
@@ -27,6 +77,17 @@ type BlockSigner interface {
 // Generator collects pending transactions and produces new blocks on
 // an interval.
 type Generator struct {
+	// RefDataValidator, if non-nil, is called by Submit with each
+	// new tx's reference data, and the tx is rejected if it returns
+	// an error. It lets a deployment require that reference data
+	// follow a schema. Reference data of inputs and outputs is not
+	// checked.
+	RefDataValidator func(refData []byte) error
+
+	// EmptyBlocks is the policy for block periods with no pending
+	// txs to include.
+	EmptyBlocks EmptyBlockPolicy
+
 	// config
 	db      pg.DB
 	chain   *protocol.Chain
@@ -35,6 +96,8 @@ type Generator struct {
 	mu         sync.Mutex
 	pool       []*legacy.Tx // in topological order
 	poolHashes map[bc.Hash]bool
+	frozen     map[bc.Hash]uint64 // output ID -> height from which it is frozen
+	paused     bool
 }
 
 // New creates and initializes a new Generator.
@@ -48,6 +111,7 @@ func New(
 		chain:      c,
 		signers:    s,
 		poolHashes: make(map[bc.Hash]bool),
+		frozen:     make(map[bc.Hash]uint64),
 	}
 }
 
@@ -62,23 +126,484 @@ func (g *Generator) PendingTxs() []*legacy.Tx {
 	return txs
 }
 
+// PoolConflicts returns the conflicts among pending txs. Two txs
+// conflict if they spend the same output or use the same issuance
+// nonce, so at most one of them can be included in a block. The
+// result maps the ID of each pending tx that has conflicts to the
+// IDs of the txs it conflicts with, in pool order. Pending txs with
+// no conflicts are omitted.
+func (g *Generator) PoolConflicts() map[bc.Hash][]bc.Hash {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	users := make(map[bc.Hash][]bc.Hash) // output or nonce ID -> IDs of txs using it
+	for _, tx := range g.pool {
+		for _, id := range tx.SpentOutputIDs {
+			users[id] = append(users[id], tx.ID)
+		}
+		for _, id := range tx.NonceIDs {
+			users[id] = append(users[id], tx.ID)
+		}
+	}
+
+	conflicts := make(map[bc.Hash][]bc.Hash)
+	seen := make(map[[2]bc.Hash]bool)
+	for _, tx := range g.pool {
+		for _, ids := range [][]bc.Hash{tx.SpentOutputIDs, tx.NonceIDs} {
+			for _, id := range ids {
+				for _, other := range users[id] {
+					if other == tx.ID || seen[[2]bc.Hash{tx.ID, other}] {
+						continue
+					}
+					seen[[2]bc.Hash{tx.ID, other}] = true
+					conflicts[tx.ID] = append(conflicts[tx.ID], other)
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+// AuditPool checks the pending tx pool against its index and reports
+// each inconsistency as an ErrPoolInconsistent with details. It
+// reports pending txs missing from the index, indexed IDs with no
+// pending tx, txs that are pending more than once, and txs that come
+// before a pending tx they spend from, which breaks the topological
+// order that removal relies on. The result is empty if the pool is
+// consistent.
+func (g *Generator) AuditPool() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	producers := make(map[bc.Hash]int) // output ID -> pool index
+	count := make(map[bc.Hash]int)
+	for i, tx := range g.pool {
+		count[tx.ID]++
+		if count[tx.ID] == 2 {
+			errs = append(errs, errors.WithDetailf(ErrPoolInconsistent, "tx %x is pending more than once", tx.ID.Bytes()))
+		}
+		if !g.poolHashes[tx.ID] {
+			errs = append(errs, errors.WithDetailf(ErrPoolInconsistent, "pending tx %x is not indexed", tx.ID.Bytes()))
+		}
+		for _, id := range tx.ResultIds {
+			producers[*id] = i
+		}
+	}
+	for id := range g.poolHashes {
+		if count[id] == 0 {
+			errs = append(errs, errors.WithDetailf(ErrPoolInconsistent, "indexed tx %x is not pending", id.Bytes()))
+		}
+	}
+	for i, tx := range g.pool {
+		for _, id := range tx.SpentOutputIDs {
+			if j, ok := producers[id]; ok && j > i {
+				errs = append(errs, errors.WithDetailf(ErrPoolInconsistent, "tx %x precedes tx %x whose output it spends", tx.ID.Bytes(), g.pool[j].ID.Bytes()))
+			}
+		}
+	}
+	return errs
+}
+
+// poolTxOverhead approximates the memory used per pending tx beyond
+// its contents: the pool slice slot and the poolHashes entry.
+const poolTxOverhead = 8 + 2*32
+
+// PoolMemoryUsage returns a rough estimate, in bytes, of the memory
+// held by the pending tx pool. Each tx is counted at twice its
+// serialized size, since it is held both as tx data and as mapped
+// entries, plus a fixed overhead for the pool's indexes.
+func (g *Generator) PoolMemoryUsage() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var n int64
+	for _, tx := range g.pool {
+		size, _ := tx.WriteTo(ioutil.Discard) // error is impossible
+		n += 2*size + poolTxOverhead
+	}
+	return n
+}
+
 // Submit adds a new pending tx to the pending tx pool.
 func (g *Generator) Submit(ctx context.Context, tx *legacy.Tx) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	if g.paused {
+		return ErrPaused
+	}
 	if g.poolHashes[tx.ID] {
 		return nil
 	}
+	if id, ok := g.spendsFrozen(tx, g.chain.Height()+1); ok {
+		return errors.WithDetailf(ErrOutputFrozen, "output %x", id.Bytes())
+	}
+	if g.RefDataValidator != nil {
+		err := g.RefDataValidator(tx.ReferenceData)
+		if err != nil {
+			return errors.Sub(ErrBadRefData, err)
+		}
+	}
 
 	g.poolHashes[tx.ID] = true
 	g.pool = append(g.pool, tx)
 	return nil
 }
 
+// Pause makes Submit reject new txs with ErrPaused until Resume is
+// called, for instance during maintenance. Txs already pending stay
+// in the pool, and blocks continue to be made from them.
+func (g *Generator) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.paused = true
+}
+
+// Resume undoes Pause, so that Submit accepts new txs again.
+func (g *Generator) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.paused = false
+}
+
+// Cancel removes a pending tx from the pending tx pool, along with
+// every pending tx that spends its outputs, directly or indirectly.
+// It returns ErrTxNotPending if the tx is not in the pool, for
+// instance because it has already been included in a block.
+//
+// Cancel affects only this generator's pool. It cannot recall a
+// transaction that has already been included in a block, nor one
+// that a client may submit again.
+func (g *Generator) Cancel(ctx context.Context, txID bc.Hash) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.poolHashes[txID] {
+		return errors.WithDetailf(ErrTxNotPending, "tx %x", txID.Bytes())
+	}
+	g.removeWithDescendants(func(tx *legacy.Tx) bool { return tx.ID == txID })
+	return nil
+}
+
+// Prioritize moves the pending tx with the given ID, along with the
+// pending txs it depends on, to the front of the pending tx pool, so
+// that the next block includes it ahead of other pending txs. That
+// matters when the pool holds more txs than fit in a block, or when
+// another pending tx conflicts with it. It returns ErrTxNotPending
+// if the tx is not in the pool.
+func (g *Generator) Prioritize(ctx context.Context, txID bc.Hash) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.poolHashes[txID] {
+		return errors.WithDetailf(ErrTxNotPending, "tx %x", txID.Bytes())
+	}
+
+	// Walk back from the tx to find its pending ancestors.
+	producers := make(map[bc.Hash]int) // output ID -> pool index
+	for i, tx := range g.pool {
+		for _, id := range tx.ResultIds {
+			producers[*id] = i
+		}
+	}
+	var (
+		first  = make(map[int]bool)
+		needed = make(map[bc.Hash]bool)
+	)
+	for i := len(g.pool) - 1; i >= 0; i-- {
+		tx := g.pool[i]
+		if tx.ID != txID && !needed[tx.ID] {
+			continue
+		}
+		first[i] = true
+		for _, id := range tx.SpentOutputIDs {
+			if j, ok := producers[id]; ok {
+				needed[g.pool[j].ID] = true
+			}
+		}
+	}
+
+	// Reorder, preserving the topological order within each part.
+	pool := make([]*legacy.Tx, 0, len(g.pool))
+	for i, tx := range g.pool {
+		if first[i] {
+			pool = append(pool, tx)
+		}
+	}
+	for i, tx := range g.pool {
+		if !first[i] {
+			pool = append(pool, tx)
+		}
+	}
+	g.pool = pool
+	return nil
+}
+
+// CheckMineable reports whether the pending tx with the given ID
+// could still be included in the next block. It returns nil if so,
+// ErrTxNotPending if the tx is not in the pool, ErrOutputFrozen if
+// it or a pending tx it depends on spends an output frozen at the
+// next height, ErrBlockFull if the valid txs ahead of it would fill
+// the block, and otherwise an error describing why the tx would be
+// dropped, for instance because a confirmed block has since spent
+// one of its prevouts.
+//
+// The check applies the pending txs ahead of it in the pool to a
+// copy of the current state, as block generation would, so a tx
+// spending the output of an earlier pending tx is mineable.
+func (g *Generator) CheckMineable(ctx context.Context, txID bc.Hash) error {
+	g.mu.Lock()
+	if !g.poolHashes[txID] {
+		g.mu.Unlock()
+		return errors.WithDetailf(ErrTxNotPending, "tx %x", txID.Bytes())
+	}
+	// Leave out the txs that making the next block would remove
+	// for spending frozen outputs, as removeFrozen does.
+	height := g.chain.Height() + 1
+	var txs []*bc.Tx
+	removedOutputs := make(map[bc.Hash]bool)
+	for _, tx := range g.pool {
+		if _, ok := g.spendsFrozen(tx, height); ok || spendsAny(tx, removedOutputs) {
+			if tx.ID == txID {
+				g.mu.Unlock()
+				return errors.WithDetailf(ErrOutputFrozen, "tx %x or a pending tx it spends from", txID.Bytes())
+			}
+			for _, id := range tx.ResultIds {
+				removedOutputs[*id] = true
+			}
+			continue
+		}
+		txs = append(txs, tx.Tx)
+		if tx.ID == txID {
+			break
+		}
+	}
+	g.mu.Unlock()
+
+	_, snapshot := g.chain.State()
+	if snapshot == nil {
+		return protocol.ErrStaleState
+	}
+	errs := g.chain.ValidateTxs(snapshot, txs, bc.Millis(time.Now()))
+	err := errs[len(errs)-1]
+	if err != nil {
+		return err
+	}
+	var ahead int
+	for _, err := range errs[:len(errs)-1] {
+		if err == nil {
+			ahead++
+		}
+	}
+	if ahead >= protocol.MaxBlockTxs {
+		return errors.WithDetailf(ErrBlockFull, "%d valid txs ahead of tx %x", ahead, txID.Bytes())
+	}
+	return nil
+}
+
+// PruneStale removes from the pending tx pool each tx that spends
+// an output that is neither unspent in the current blockchain state
+// nor created by an earlier pending tx, typically because a
+// confirmed block has spent it. Descendants of removed txs are
+// removed too. It returns the number of txs removed.
+func (g *Generator) PruneStale(ctx context.Context) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, snapshot := g.chain.State()
+	if snapshot == nil {
+		return 0, protocol.ErrStaleState
+	}
+	created := make(map[bc.Hash]bool)
+	n := g.removeWithDescendants(func(tx *legacy.Tx) bool {
+		for _, id := range tx.SpentOutputIDs {
+			if !created[id] && !snapshot.Tree.Contains(id.Bytes()) {
+				return true
+			}
+		}
+		for _, id := range tx.ResultIds {
+			created[*id] = true
+		}
+		return false
+	})
+	return n, nil
+}
+
+// FreezeOutput prevents the output with the given ID from being
+// spent in blocks made by this generator. Pending txs that spend it,
+// and their descendants, are removed from the pending tx pool, and
+// Submit rejects new ones with ErrOutputFrozen.
+//
+// Freezing is generator policy, not consensus: it does not affect
+// validation of blocks. Frozen outputs are saved in the database and
+// reloaded by LoadFrozen, so they survive a restart.
+func (g *Generator) FreezeOutput(ctx context.Context, outputID bc.Hash) error {
+	return g.freeze(ctx, outputID, 0)
+}
+
+// ScheduleFreeze is like FreezeOutput, but the freeze takes effect
+// only for blocks at or above effectiveHeight. Until then, txs
+// spending the output are accepted; any still pending when the
+// freeze takes effect are removed from the pool before the block at
+// effectiveHeight is made.
+func (g *Generator) ScheduleFreeze(ctx context.Context, outputID bc.Hash, effectiveHeight uint64) error {
+	g.mu.Lock()
+	h, ok := g.frozen[outputID]
+	g.mu.Unlock()
+	if ok && h <= effectiveHeight {
+		return nil // already frozen by then
+	}
+	return g.freeze(ctx, outputID, effectiveHeight)
+}
+
+// freeze records that outputID is frozen from the given height,
+// unless it is already frozen from an earlier one, and removes any
+// pending spends of it if the freeze applies to the next block.
+// The freeze is saved before g.mu is taken, so Submit is not held
+// up by the database.
+func (g *Generator) freeze(ctx context.Context, outputID bc.Hash, height uint64) error {
+	err := saveFrozenOutput(ctx, g.db, outputID, height)
+	if err != nil {
+		return errors.Wrap(err, "saving frozen output")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if h, ok := g.frozen[outputID]; !ok || height < h {
+		g.frozen[outputID] = height
+	}
+	g.removeFrozen(g.chain.Height() + 1)
+	return nil
+}
+
+// LoadFrozen replaces g's frozen outputs with those saved in the
+// database and removes any pending spends of them that the freezes
+// apply to. It should be called before g serves Submit, so that txs
+// spending outputs frozen before a restart are rejected. Generate
+// calls it again when it starts, to pick up freezes made by another
+// process while this one was not the leader.
+func (g *Generator) LoadFrozen(ctx context.Context) error {
+	frozen, err := getFrozenOutputs(ctx, g.db)
+	if err != nil {
+		return errors.Wrap(err, "loading frozen outputs")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.frozen = frozen
+	g.removeFrozen(g.chain.Height() + 1)
+	return nil
+}
+
+// removeFrozen removes from the pool each tx that spends an output
+// frozen at the given block height, along with its descendants.
+// The caller must hold g.mu.
+func (g *Generator) removeFrozen(height uint64) {
+	g.removeWithDescendants(func(tx *legacy.Tx) bool {
+		_, ok := g.spendsFrozen(tx, height)
+		return ok
+	})
+}
+
+// spendsFrozen returns the ID of an output tx spends that is frozen
+// at the given block height, if there is one.
+func (g *Generator) spendsFrozen(tx *legacy.Tx, height uint64) (bc.Hash, bool) {
+	for _, id := range tx.SpentOutputIDs {
+		if h, ok := g.frozen[id]; ok && h <= height {
+			return id, true
+		}
+	}
+	return bc.Hash{}, false
+}
+
+// UnfreezeOutput undoes the effect of FreezeOutput or ScheduleFreeze.
+// Txs removed from the pool when the output was frozen must be
+// submitted again.
+func (g *Generator) UnfreezeOutput(ctx context.Context, outputID bc.Hash) error {
+	err := deleteFrozenOutput(ctx, g.db, outputID)
+	if err != nil {
+		return errors.Wrap(err, "deleting frozen output")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.frozen, outputID)
+	return nil
+}
+
+// getFrozenOutputs retrieves the frozen outputs saved in the database,
+// mapping each output ID to the height from which it is frozen.
+func getFrozenOutputs(ctx context.Context, db pg.DB) (map[bc.Hash]uint64, error) {
+	const q = `SELECT output_id, height FROM generator_frozen_outputs`
+	frozen := make(map[bc.Hash]uint64)
+	err := pg.ForQueryRows(ctx, db, q, func(outputID bc.Hash, height uint64) {
+		frozen[outputID] = height
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "generator_frozen_outputs query")
+	}
+	return frozen, nil
+}
+
+// saveFrozenOutput persists the freeze of outputID from the given
+// height. If outputID is already frozen, the lower height is kept.
+func saveFrozenOutput(ctx context.Context, db pg.DB, outputID bc.Hash, height uint64) error {
+	const q = `
+		INSERT INTO generator_frozen_outputs (output_id, height) VALUES($1, $2)
+		ON CONFLICT (output_id) DO UPDATE
+		SET height = LEAST(generator_frozen_outputs.height, excluded.height)
+	`
+	_, err := db.ExecContext(ctx, q, outputID, height)
+	return errors.Wrap(err, "generator_frozen_outputs insert query")
+}
+
+// deleteFrozenOutput removes any saved freeze of outputID.
+func deleteFrozenOutput(ctx context.Context, db pg.DB, outputID bc.Hash) error {
+	const q = `DELETE FROM generator_frozen_outputs WHERE output_id = $1`
+	_, err := db.ExecContext(ctx, q, outputID)
+	return errors.Wrap(err, "generator_frozen_outputs delete query")
+}
+
+// removeWithDescendants removes from the pool each tx for which
+// remove returns true, along with each tx that spends an output of a
+// removed tx. It relies on the pool being in topological order.
+// It returns the number of txs removed. The caller must hold g.mu.
+func (g *Generator) removeWithDescendants(remove func(*legacy.Tx) bool) int {
+	var (
+		kept           []*legacy.Tx
+		removedOutputs = make(map[bc.Hash]bool)
+	)
+	for _, tx := range g.pool {
+		if !remove(tx) && !spendsAny(tx, removedOutputs) {
+			kept = append(kept, tx)
+			continue
+		}
+		for _, id := range tx.ResultIds {
+			removedOutputs[*id] = true
+		}
+		delete(g.poolHashes, tx.ID)
+	}
+	n := len(g.pool) - len(kept)
+	g.pool = kept
+	return n
+}
+
+func spendsAny(tx *legacy.Tx, outputIDs map[bc.Hash]bool) bool {
+	for _, id := range tx.SpentOutputIDs {
+		if outputIDs[id] {
+			return true
+		}
+	}
+	return false
+}
+
 // Generate runs in a loop, making one new block
 // every block period. It returns when its context
 // is canceled.
+// Before making its first block, it loads the outputs
+// frozen by FreezeOutput and ScheduleFreeze with LoadFrozen,
+// retrying each block period until that succeeds.
 // After each attempt to make a block, it calls health
 // to report either an error or nil to indicate success.
 func (g *Generator) Generate(
@@ -87,13 +612,25 @@ func (g *Generator) Generate(
 	health func(error),
 ) {
 	ticks := time.Tick(period)
+	err := g.LoadFrozen(ctx)
+	if err != nil {
+		log.Error(ctx, err)
+	}
+	loaded := err == nil
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf(ctx, "Deposed, Generate exiting")
 			return
 		case <-ticks:
-			err := g.makeBlock(ctx)
+			err = nil
+			if !loaded {
+				err = g.LoadFrozen(ctx)
+				loaded = err == nil
+			}
+			if err == nil {
+				err = g.makeBlock(ctx)
+			}
 			health(err)
 			if err != nil {
 				log.Error(ctx, err)
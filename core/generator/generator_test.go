@@ -2,17 +2,22 @@ package generator
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"chain/crypto/ed25519"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol"
+	"chain/protocol/bc"
 	"chain/protocol/bc/bctest"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
+	"chain/protocol/vm"
 	"chain/testutil"
 )
 
@@ -51,6 +56,32 @@ func TestGeneratorRecovery(t *testing.T) {
 	}
 }
 
+func TestEmptyBlockPolicy(t *testing.T) {
+	cases := []struct {
+		policy     EmptyBlockPolicy
+		wantErr    error
+		wantHeight uint64
+	}{
+		{SkipEmptyBlocks, nil, 1},
+		{MakeEmptyBlocks, nil, 2},
+		{FailEmptyBlocks, ErrNoPendingTxs, 1},
+	}
+	for _, test := range cases {
+		ctx := context.Background()
+		c := prottest.NewChain(t)
+		g := New(c, nil, pgtest.NewTx(t))
+		g.EmptyBlocks = test.policy
+
+		err := g.makeBlock(ctx)
+		if errors.Root(err) != test.wantErr {
+			t.Errorf("policy %d: makeBlock() = %v want %v", test.policy, err, test.wantErr)
+		}
+		if h := c.Height(); h != test.wantHeight {
+			t.Errorf("policy %d: height = %d want %d", test.policy, h, test.wantHeight)
+		}
+	}
+}
+
 func TestGeneratorSignatureFailures(t *testing.T) {
 	ctx := context.Background()
 	c := prottest.NewChain(t, prottest.WithBlockSigners(1, 1))
@@ -164,6 +195,551 @@ func TestGetAndAddBlockSignaturesInitialBlock(t *testing.T) {
 	}
 }
 
+func TestPause(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+
+	pending := issueTrue(t, c)
+	err := g.Submit(ctx, pending)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	g.Pause()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		tx := issueTrue(t, c)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := g.Submit(ctx, tx)
+			if err != ErrPaused {
+				t.Errorf("Submit while paused = %v want %v", err, ErrPaused)
+			}
+		}()
+	}
+	wg.Wait()
+	if txs := g.PendingTxs(); len(txs) != 1 || txs[0].ID != pending.ID {
+		t.Errorf("got %d pending txs while paused, want only the tx submitted before", len(txs))
+	}
+
+	g.Resume()
+	err = g.Submit(ctx, issueTrue(t, c))
+	if err != nil {
+		t.Errorf("Submit after Resume = %v want nil", err)
+	}
+	if n := len(g.PendingTxs()); n != 2 {
+		t.Errorf("got %d pending txs after Resume, want 2", n)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c).Hash()
+	g := New(c, nil, nil)
+
+	parent := bctest.NewIssuanceTx(t, initial)
+	child := spendOutput(t, parent, 0)
+	unrelated := bctest.NewIssuanceTx(t, initial)
+
+	for _, tx := range []*legacy.Tx{parent, child, unrelated} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	err := g.Cancel(ctx, parent.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	pending := g.PendingTxs()
+	if len(pending) != 1 || pending[0].ID != unrelated.ID {
+		t.Errorf("after Cancel, got %d pending txs, want only the unrelated tx", len(pending))
+	}
+	if g.poolHashes[child.ID] {
+		t.Error("descendant of canceled tx still in pool index")
+	}
+
+	err = g.Cancel(ctx, parent.ID)
+	if errors.Root(err) != ErrTxNotPending {
+		t.Errorf("second Cancel: got error %v, want %v", err, ErrTxNotPending)
+	}
+	err = g.Cancel(ctx, bc.Hash{})
+	if errors.Root(err) != ErrTxNotPending {
+		t.Errorf("Cancel of unknown tx: got error %v, want %v", err, ErrTxNotPending)
+	}
+}
+
+func TestPoolConflicts(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+
+	issueTx := issueTrue(t, c)
+	spend1 := spendOutput(t, issueTx, 0)
+	spend2 := spendOutput(t, issueTx, 0)
+	spend2.ReferenceData = []byte("conflict")
+	*spend2 = *legacy.NewTx(spend2.TxData)
+	unrelated := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+
+	for _, tx := range []*legacy.Tx{issueTx, spend1, spend2, unrelated} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	got := g.PoolConflicts()
+	want := map[bc.Hash][]bc.Hash{
+		spend1.ID: {spend2.ID},
+		spend2.ID: {spend1.ID},
+	}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("PoolConflicts() = %x want %x", got, want)
+	}
+}
+
+func TestRefDataValidator(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+	g.RefDataValidator = func(refData []byte) error {
+		var v struct{ Invoice string }
+		err := json.Unmarshal(refData, &v)
+		if err != nil {
+			return err
+		}
+		if v.Invoice == "" {
+			return errors.New("missing invoice")
+		}
+		return nil
+	}
+
+	cases := []struct {
+		refData []byte
+		wantErr error
+	}{
+		{[]byte(`{"invoice":"1234"}`), nil},
+		{[]byte(`{"memo":"hello"}`), ErrBadRefData},
+		{nil, ErrBadRefData},
+	}
+	for _, test := range cases {
+		tx := issueTrue(t, c)
+		tx.ReferenceData = test.refData
+		*tx = *legacy.NewTx(tx.TxData)
+		err := g.Submit(ctx, tx)
+		if errors.Root(err) != test.wantErr {
+			t.Errorf("Submit(tx with ref data %q) = %v want %v", test.refData, err, test.wantErr)
+		}
+	}
+	if n := len(g.PendingTxs()); n != 1 {
+		t.Errorf("got %d pending txs, want 1", n)
+	}
+}
+
+func TestAuditPool(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+
+	parent := issueTrue(t, c)
+	child := spendOutput(t, parent, 0)
+	for _, tx := range []*legacy.Tx{parent, child} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	if errs := g.AuditPool(); len(errs) != 0 {
+		t.Fatalf("AuditPool() on consistent pool = %v", errs)
+	}
+
+	// Plant inconsistencies directly.
+	g.pool[0], g.pool[1] = g.pool[1], g.pool[0]
+	delete(g.poolHashes, parent.ID)
+	g.poolHashes[bc.Hash{}] = true
+
+	errs := g.AuditPool()
+	if len(errs) != 3 {
+		t.Fatalf("AuditPool() = %v, want 3 errors", errs)
+	}
+	for _, err := range errs {
+		if errors.Root(err) != ErrPoolInconsistent {
+			t.Errorf("AuditPool() error %v, want %v", err, ErrPoolInconsistent)
+		}
+	}
+}
+
+func TestFreezeOutput(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+
+	issueTx := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	spendTx := spendOutput(t, issueTx, 0)
+	outputID := *issueTx.OutputID(0)
+
+	err := g.Submit(ctx, issueTx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.Submit(ctx, spendTx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	err = g.FreezeOutput(ctx, outputID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	pending := g.PendingTxs()
+	if len(pending) != 1 || pending[0].ID != issueTx.ID {
+		t.Errorf("after FreezeOutput, got %d pending txs, want only the issuance", len(pending))
+	}
+	err = g.Submit(ctx, spendTx)
+	if errors.Root(err) != ErrOutputFrozen {
+		t.Errorf("Submit of frozen spend: got error %v, want %v", err, ErrOutputFrozen)
+	}
+
+	err = g.UnfreezeOutput(ctx, outputID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.Submit(ctx, spendTx)
+	if err != nil {
+		t.Errorf("Submit after UnfreezeOutput: got error %v", err)
+	}
+	if len(g.PendingTxs()) != 2 {
+		t.Errorf("got %d pending txs, want 2", len(g.PendingTxs()))
+	}
+}
+
+func TestFreezeOutputPersists(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	dbtx := pgtest.NewTx(t)
+	g := New(c, nil, dbtx)
+
+	issueTx := issueTrue(t, c)
+	prottest.MakeBlock(t, c, []*legacy.Tx{issueTx})
+	frozenID := *issueTx.OutputID(0)
+	scheduledID := bc.NewHash([32]byte{1})
+
+	err := g.FreezeOutput(ctx, frozenID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.ScheduleFreeze(ctx, scheduledID, c.Height()+5)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// A generator made after a restart picks up the saved freezes.
+	g = New(c, nil, dbtx)
+	err = g.LoadFrozen(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := map[bc.Hash]uint64{frozenID: 0, scheduledID: c.Height() + 5}
+	if !testutil.DeepEqual(g.frozen, want) {
+		t.Errorf("frozen outputs after restart = %v, want %v", g.frozen, want)
+	}
+	err = g.Submit(ctx, spendOutput(t, issueTx, 0))
+	if errors.Root(err) != ErrOutputFrozen {
+		t.Errorf("Submit of frozen spend after restart: got error %v, want %v", err, ErrOutputFrozen)
+	}
+
+	err = g.UnfreezeOutput(ctx, frozenID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	g = New(c, nil, dbtx)
+	err = g.LoadFrozen(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if _, ok := g.frozen[frozenID]; ok {
+		t.Error("unfrozen output still frozen after restart")
+	}
+}
+
+func TestScheduleFreeze(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+
+	issueTx := issueTrue(t, c)
+	prottest.MakeBlock(t, c, []*legacy.Tx{issueTx})
+	outputID := *issueTx.OutputID(0)
+	spendTx := spendOutput(t, issueTx, 0)
+	childTx := spendOutput(t, spendTx, 0)
+	otherTx := issueTrue(t, c)
+
+	err := g.ScheduleFreeze(ctx, outputID, c.Height()+2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for _, tx := range []*legacy.Tx{spendTx, childTx, otherTx} {
+		err = g.Submit(ctx, tx)
+		if err != nil {
+			t.Errorf("Submit before freeze takes effect: got error %v", err)
+		}
+	}
+
+	// The next block the generator makes is at the effective height,
+	// so it must leave out the spend and its descendant.
+	prottest.MakeBlock(t, c, nil)
+	err = g.makeBlock(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	b, err := c.GetBlock(ctx, c.Height())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(b.Transactions) != 1 || b.Transactions[0].ID != otherTx.ID {
+		t.Errorf("block at effective height has %d txs, want only the unrelated issuance", len(b.Transactions))
+	}
+	if n := len(g.PendingTxs()); n != 0 {
+		t.Errorf("got %d pending txs after freeze took effect, want 0", n)
+	}
+	err = g.Submit(ctx, spendTx)
+	if errors.Root(err) != ErrOutputFrozen {
+		t.Errorf("Submit after freeze takes effect: got error %v, want %v", err, ErrOutputFrozen)
+	}
+}
+
+func TestCheckMineable(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+
+	issueTx := issueTrue(t, c)
+	spendTx := spendOutput(t, issueTx, 0)
+	for _, tx := range []*legacy.Tx{issueTx, spendTx} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	err := g.CheckMineable(ctx, spendTx.ID)
+	if err != nil {
+		t.Errorf("spend of pending issuance: got error %v, want nil", err)
+	}
+
+	// Confirm the issuance and its spend, then submit an alternative
+	// spend of the same output.
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	g = New(c, nil, nil)
+	doubleSpend := spendOutput(t, issueTx, 0)
+	doubleSpend.ReferenceData = []byte("double spend")
+	*doubleSpend = *legacy.NewTx(doubleSpend.TxData)
+	err = g.Submit(ctx, doubleSpend)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.CheckMineable(ctx, doubleSpend.ID)
+	if errors.Root(err) != protocol.ErrBadTx {
+		t.Errorf("spend of spent output: got error %v, want %v", err, protocol.ErrBadTx)
+	}
+
+	// An earlier pending tx that fails must not affect a later one,
+	// even if it fails only after its nonce is applied.
+	g = New(c, nil, nil)
+	goodTx := issueTrue(t, c)
+	badData := goodTx.TxData
+	badSpend := spendOutput(t, issueTrue(t, c), 0) // prevout not issued
+	badData.Inputs = append(append([]*legacy.TxInput{}, goodTx.Inputs...), badSpend.Inputs...)
+	badData.Outputs = append(append([]*legacy.TxOutput{}, goodTx.Outputs...), badSpend.Outputs...)
+	badTx := legacy.NewTx(badData)
+	for _, tx := range []*legacy.Tx{badTx, goodTx} {
+		err = g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	err = g.CheckMineable(ctx, badTx.ID)
+	if errors.Root(err) != protocol.ErrBadTx {
+		t.Errorf("tx with unissued prevout: got error %v, want %v", err, protocol.ErrBadTx)
+	}
+	err = g.CheckMineable(ctx, goodTx.ID)
+	if err != nil {
+		t.Errorf("tx after invalid tx with the same nonce: got error %v, want nil", err)
+	}
+
+	// A freeze in effect at the next height makes both a spend of the
+	// frozen output and its descendant unmineable.
+	g = New(c, nil, nil)
+	frozenTx := issueTrue(t, c)
+	prottest.MakeBlock(t, c, []*legacy.Tx{frozenTx})
+	spendTx = spendOutput(t, frozenTx, 0)
+	childTx := spendOutput(t, spendTx, 0)
+	for _, tx := range []*legacy.Tx{spendTx, childTx} {
+		err = g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	g.frozen[*frozenTx.OutputID(0)] = c.Height() + 1
+	for _, tx := range []*legacy.Tx{spendTx, childTx} {
+		err = g.CheckMineable(ctx, tx.ID)
+		if errors.Root(err) != ErrOutputFrozen {
+			t.Errorf("tx depending on frozen output: got error %v, want %v", err, ErrOutputFrozen)
+		}
+	}
+
+	err = g.CheckMineable(ctx, bc.Hash{})
+	if errors.Root(err) != ErrTxNotPending {
+		t.Errorf("unknown tx: got error %v, want %v", err, ErrTxNotPending)
+	}
+}
+
+func TestPruneStale(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	initial := prottest.Initial(t, c).Hash()
+	g := New(c, nil, nil)
+
+	confirmed := issueTrue(t, c)
+	prottest.MakeBlock(t, c, []*legacy.Tx{confirmed})
+	spend := spendOutput(t, confirmed, 0)
+	prottest.MakeBlock(t, c, []*legacy.Tx{spend})
+
+	// Two conflicting spends of an already-spent output, one with a
+	// pending child, and an unrelated chain of pending txs.
+	stale1 := spendOutput(t, confirmed, 0)
+	stale1.ReferenceData = []byte("stale 1")
+	*stale1 = *legacy.NewTx(stale1.TxData)
+	stale2 := spendOutput(t, confirmed, 0)
+	stale2.ReferenceData = []byte("stale 2")
+	*stale2 = *legacy.NewTx(stale2.TxData)
+	staleChild := spendOutput(t, stale2, 0)
+	fresh := bctest.NewIssuanceTx(t, initial)
+	freshChild := spendOutput(t, fresh, 0)
+
+	for _, tx := range []*legacy.Tx{stale1, fresh, stale2, staleChild, freshChild} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	n, err := g.PruneStale(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if n != 3 {
+		t.Errorf("PruneStale removed %d txs, want 3", n)
+	}
+	pending := g.PendingTxs()
+	if len(pending) != 2 || pending[0].ID != fresh.ID || pending[1].ID != freshChild.ID {
+		t.Errorf("after PruneStale, got %d pending txs, want fresh and its child", len(pending))
+	}
+}
+
+func TestPrioritize(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+
+	other := issueTrue(t, c)
+	parent := issueTrue(t, c)
+	competing := spendOutput(t, parent, 0)
+	competing.ReferenceData = []byte("competing")
+	*competing = *legacy.NewTx(competing.TxData)
+	child := spendOutput(t, parent, 0)
+
+	for _, tx := range []*legacy.Tx{other, parent, competing, child} {
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	err := g.Prioritize(ctx, child.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	var got []bc.Hash
+	for _, tx := range g.PendingTxs() {
+		got = append(got, tx.ID)
+	}
+	want := []bc.Hash{parent.ID, child.ID, other.ID, competing.ID}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("after Prioritize, pool order = %x want %x", got, want)
+	}
+
+	// The prioritized spend wins over the one submitted before it.
+	block := prottest.MakeBlock(t, c, g.PendingTxs())
+	var included bool
+	for _, tx := range block.Transactions {
+		if tx.ID == competing.ID {
+			t.Error("competing spend included in block")
+		}
+		included = included || tx.ID == child.ID
+	}
+	if !included {
+		t.Error("prioritized tx not included in block")
+	}
+
+	err = g.Prioritize(ctx, bc.Hash{})
+	if errors.Root(err) != ErrTxNotPending {
+		t.Errorf("unknown tx: got error %v, want %v", err, ErrTxNotPending)
+	}
+}
+
+func TestPoolMemoryUsage(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+
+	var prev int64
+	for i := 0; i < 3; i++ {
+		err := g.Submit(ctx, issueTrue(t, c))
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		got := g.PoolMemoryUsage()
+		if got <= prev {
+			t.Errorf("after %d txs, PoolMemoryUsage = %d, want more than %d", i+1, got, prev)
+		}
+		prev = got
+	}
+}
+
+// issueTrue returns an issuance tx whose output
+// is controlled by a program anyone can satisfy.
+func issueTrue(tb testing.TB, c *protocol.Chain) *legacy.Tx {
+	return bctest.NewIssuanceTx(tb, prottest.Initial(tb, c).Hash(), func(tx *legacy.Tx) {
+		tx.Outputs[0].ControlProgram = []byte{byte(vm.OP_TRUE)}
+		*tx = *legacy.NewTx(tx.TxData)
+	})
+}
+
+// spendOutput returns a tx spending output index of prev
+// to a garbage control program.
+func spendOutput(tb testing.TB, prev *legacy.Tx, index int) *legacy.Tx {
+	out, err := prev.Tx.Output(*prev.OutputID(index))
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	val := out.Source.Value
+	return legacy.NewTx(legacy.TxData{
+		Version: 1,
+		MinTime: prev.MinTime,
+		MaxTime: prev.MaxTime,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, *out.Source.Ref, *val.AssetId, val.Amount, out.Source.Position, out.ControlProgram.Code, *out.Data, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*val.AssetId, val.Amount, []byte{0xbe, 0xef}, nil),
+		},
+	})
+}
+
 type testSigner struct {
 	before  func() error
 	pubKey  ed25519.PublicKey
@@ -72,4 +72,10 @@ var migrations = []migration{
 		DROP INDEX signers_type_id_idx;
 		DROP INDEX assets_sort_id;
 	`},
+	{Name: `2017-05-15.0.generator.frozen-outputs.sql`, SQL: `
+		CREATE TABLE generator_frozen_outputs (
+			output_id bytea PRIMARY KEY,
+			height bigint NOT NULL
+		);
+	`},
 }
@@ -1,6 +1,7 @@
 package migrate
 
 import (
+	"io/ioutil"
 	"regexp"
 	"testing"
 )
@@ -30,3 +31,29 @@ func TestNames(t *testing.T) {
 		}
 	}
 }
+
+var schemaMigration = regexp.MustCompile(`(?m)^insert into migrations \(filename, hash\) values \('([^']+)', '([0-9a-f]+)'\);$`)
+
+// TestSchemaHashes checks that core/schema.sql, as regenerated by
+// cmd/dumpschema, records the hash of each migration's SQL.
+func TestSchemaHashes(t *testing.T) {
+	schema, err := ioutil.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := make(map[string]string)
+	for _, m := range schemaMigration.FindAllStringSubmatch(string(schema), -1) {
+		hashes[m[1]] = m[2]
+	}
+	for _, m := range migrations {
+		h, ok := hashes[m.Name]
+		if !ok {
+			t.Errorf("schema.sql is missing migration %s", m.Name)
+		} else if h != m.Hash {
+			t.Errorf("schema.sql hash of %s = %s, want %s", m.Name, h, m.Hash)
+		}
+	}
+	if len(hashes) != len(migrations) {
+		t.Errorf("schema.sql has %d migrations, want %d", len(hashes), len(migrations))
+	}
+}
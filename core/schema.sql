@@ -340,6 +340,13 @@ CREATE TABLE config (
 
 
 
+CREATE TABLE generator_frozen_outputs (
+    output_id bytea NOT NULL,
+    height bigint NOT NULL
+);
+
+
+
 CREATE TABLE generator_pending_block (
     singleton boolean DEFAULT true NOT NULL,
     data bytea NOT NULL,
@@ -549,6 +556,11 @@ ALTER TABLE ONLY config
 
 
 
+ALTER TABLE ONLY generator_frozen_outputs
+    ADD CONSTRAINT generator_frozen_outputs_pkey PRIMARY KEY (output_id);
+
+
+
 ALTER TABLE ONLY generator_pending_block
     ADD CONSTRAINT generator_pending_block_pkey PRIMARY KEY (singleton);
 
@@ -654,3 +666,4 @@ insert into migrations (filename, hash) values ('2017-04-13.0.query.block-transa
 insert into migrations (filename, hash) values ('2017-04-17.0.core.null-token-type.sql', '185942cec464c12a2573f19ae386153389328f8e282af071024706e105e37eeb');
 insert into migrations (filename, hash) values ('2017-04-27.0.generator.pending-block-height.sql', 'bfe4fe5eec143e4367a91fd952cb5e3879f1c311f649ec13bfe95b202e94d4ec');
 insert into migrations (filename, hash) values ('2017-05-08.0.core.drop-redundant-indexes.sql', '5140e53b287b058c57ddf361d61cff3d3d1cbc3259a9de413b11574a71d09bec');
+insert into migrations (filename, hash) values ('2017-05-15.0.generator.frozen-outputs.sql', '7a96a52ea6a5751482d375f55b1f503c1d746bdeef2b670713b60e11fe6cdf82');
@@ -1,8 +1,10 @@
 package txdb
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"sort"
 
 	"github.com/golang/protobuf/proto"
 
@@ -45,7 +47,12 @@ func DecodeSnapshot(data []byte) (*state.Snapshot, error) {
 	}, nil
 }
 
-func storeStateSnapshot(ctx context.Context, db pg.DB, snapshot *state.Snapshot, blockHeight uint64) error {
+// EncodeSnapshot encodes a snapshot in the Chain Core's binary,
+// protobuf representation, the inverse of DecodeSnapshot. The
+// encoding is deterministic: state tree items appear in tree order
+// and nonces are sorted by hash, so equal snapshots encode to equal
+// bytes and an encoded snapshot may be hashed or compared.
+func EncodeSnapshot(snapshot *state.Snapshot) ([]byte, error) {
 	var storedSnapshot storage.Snapshot
 	err := patricia.Walk(snapshot.Tree, func(key []byte) error {
 		n := &storage.Snapshot_StateTreeNode{Key: key}
@@ -53,7 +60,7 @@ func storeStateSnapshot(ctx context.Context, db pg.DB, snapshot *state.Snapshot,
 		return nil
 	})
 	if err != nil {
-		return errors.Wrap(err, "walking patricia tree")
+		return nil, errors.Wrap(err, "walking patricia tree")
 	}
 
 	storedSnapshot.Nonces = make([]*storage.Snapshot_Nonce, 0, len(snapshot.Nonces))
@@ -64,10 +71,18 @@ func storeStateSnapshot(ctx context.Context, db pg.DB, snapshot *state.Snapshot,
 			ExpiryMs: v,
 		})
 	}
+	sort.Slice(storedSnapshot.Nonces, func(i, j int) bool {
+		return bytes.Compare(storedSnapshot.Nonces[i].Hash, storedSnapshot.Nonces[j].Hash) < 0
+	})
 
 	b, err := proto.Marshal(&storedSnapshot)
+	return b, errors.Wrap(err, "marshaling state snapshot")
+}
+
+func storeStateSnapshot(ctx context.Context, db pg.DB, snapshot *state.Snapshot, blockHeight uint64) error {
+	b, err := EncodeSnapshot(snapshot)
 	if err != nil {
-		return errors.Wrap(err, "marshaling state snapshot")
+		return err
 	}
 
 	const insertQ = `
@@ -1,6 +1,7 @@
 package txdb
 
 import (
+	"bytes"
 	"context"
 	"math/rand"
 	"testing"
@@ -122,6 +123,42 @@ func TestReadWriteStateSnapshot(t *testing.T) {
 	}
 }
 
+func TestEncodeSnapshot(t *testing.T) {
+	snapshot := state.Empty()
+	for i := byte(1); i <= 5; i++ {
+		err := snapshot.Tree.Insert(bc.NewHash([32]byte{i}).Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot.Nonces[bc.NewHash([32]byte{0xf0 | i})] = uint64(i) * 1000
+	}
+
+	data, err := EncodeSnapshot(snapshot)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		again, err := EncodeSnapshot(snapshot)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Fatalf("encoding %d differs from first encoding", i)
+		}
+	}
+
+	got, err := DecodeSnapshot(data)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.Tree.RootHash() != snapshot.Tree.RootHash() {
+		t.Errorf("decoded tree root = %x want %x", got.Tree.RootHash().Bytes(), snapshot.Tree.RootHash().Bytes())
+	}
+	if !testutil.DeepEqual(got.Nonces, snapshot.Nonces) {
+		t.Errorf("decoded nonces = %#v want %#v", got.Nonces, snapshot.Nonces)
+	}
+}
+
 func BenchmarkStoreSnapshot100(b *testing.B) {
 	benchmarkStoreSnapshot(100, 100, b)
 }
@@ -35,6 +35,11 @@ func (ad *AssetDefinition) ComputeAssetID() (assetID AssetID) {
 	return NewAssetID(b)
 }
 
+// ComputeAssetID computes the ID of the asset issued by the given
+// issuance program on the blockchain with the given initial block
+// ID, with data the hash of the asset definition. It is the same
+// derivation validation checks issuances against, so clients can
+// compute an asset ID before issuing.
 func ComputeAssetID(prog []byte, initialBlockID *Hash, vmVersion uint64, data *Hash) AssetID {
 	def := &AssetDefinition{
 		InitialBlockId: initialBlockID,
@@ -12,7 +12,15 @@ var (
 )
 
 // MerkleRoot creates a merkle tree from a slice of transactions
-// and returns the root hash of the tree.
+// and returns the root hash of the tree. This is the
+// TransactionsMerkleRoot that block validation expects.
+//
+// The root of an empty list is EmptyStringHash. A leaf is the hash
+// of 0x00 followed by a tx ID. A list of n > 1 transactions is split
+// at the largest power of two less than n, and its root is the hash
+// of 0x01 followed by the roots of the two parts. An odd final leaf
+// is never duplicated, so appending a copy of the last tx changes
+// the root.
 func MerkleRoot(transactions []*Tx) (root Hash, err error) {
 	switch {
 	case len(transactions) == 0:
@@ -14,6 +14,9 @@ func TestMerkleRoot(t *testing.T) {
 		witnesses [][][]byte
 		want      Hash
 	}{{
+		witnesses: nil,
+		want:      EmptyStringHash,
+	}, {
 		witnesses: [][][]byte{
 			[][]byte{
 				{1},
@@ -46,6 +49,22 @@ func TestMerkleRoot(t *testing.T) {
 			},
 		},
 		want: mustDecodeHash("526737fcca853f5ad352081c5a7341aca4ee05b09a002c8600e26a06df02aa3b"),
+	}, {
+		witnesses: [][][]byte{
+			[][]byte{
+				{1},
+				[]byte("000000"),
+			},
+			[][]byte{
+				{1},
+				[]byte("111111"),
+			},
+			[][]byte{
+				{1},
+				[]byte("222222"),
+			},
+		},
+		want: mustDecodeHash("c73303de33841e28647819986952b0ca7442e38184e69ba78e777596c13cfbbe"),
 	}}
 
 	for _, c := range cases {
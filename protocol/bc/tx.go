@@ -17,6 +17,12 @@ type Tx struct {
 	SpentOutputIDs []Hash
 }
 
+// SigHash returns the hash that the TXSIGHASH instruction pushes
+// when evaluating the program of input n: the hash of the input's
+// entry ID and the tx ID. External signers can sign it to satisfy a
+// program that checks a signature against TXSIGHASH. Since it
+// commits to the tx ID, it is fixed once everything but the
+// witness arguments is settled.
 func (tx *Tx) SigHash(n uint32) (hash Hash) {
 	hasher := sha3pool.Get256()
 	defer sha3pool.Put256(hasher)
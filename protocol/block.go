@@ -8,6 +8,7 @@ import (
 	"chain/crypto/ed25519"
 	"chain/errors"
 	"chain/log"
+	"chain/math/checked"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
@@ -15,9 +16,9 @@ import (
 	"chain/protocol/vm/vmutil"
 )
 
-// maxBlockTxs limits the number of transactions
+// MaxBlockTxs limits the number of transactions
 // included in each block.
-const maxBlockTxs = 10000
+const MaxBlockTxs = 10000
 
 // saveSnapshotFrequency stores how often to save a state
 // snapshot to the Store.
@@ -34,6 +35,14 @@ var (
 	// ErrBadStateRoot is returned when the computed assets merkle root
 	// disagrees with the one declared in a block header.
 	ErrBadStateRoot = errors.New("invalid state merkle root")
+
+	// ErrBadHeightRange is returned when a range of block heights
+	// starts above where it ends.
+	ErrBadHeightRange = errors.New("invalid block height range")
+
+	// ErrSupplyOverflow is returned when a block's total issuance or
+	// retirement of an asset does not fit in a uint64.
+	ErrSupplyOverflow = errors.New("asset supply overflow")
 )
 
 // GetBlock returns the block at the given height, if there is one,
@@ -42,6 +51,138 @@ func (c *Chain) GetBlock(ctx context.Context, height uint64) (*legacy.Block, err
 	return c.store.GetBlock(ctx, height)
 }
 
+// BlockCursor reads blocks from a Chain's store in height order.
+// It is created by BlocksFrom.
+type BlockCursor struct {
+	c      *Chain
+	height uint64 // height of the next block to read
+}
+
+// BlocksFrom returns a cursor that reads blocks sequentially,
+// starting with the block at startHeight. Heights begin at 1;
+// a startHeight of 0 is treated as 1.
+func (c *Chain) BlocksFrom(ctx context.Context, startHeight uint64) (*BlockCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if startHeight == 0 {
+		startHeight = 1
+	}
+	return &BlockCursor{c: c, height: startHeight}, nil
+}
+
+// Next returns the next block. If the cursor has reached the tip
+// of the blockchain, Next waits for a new block to land or for ctx
+// to be canceled, in which case it returns ctx.Err().
+func (cur *BlockCursor) Next(ctx context.Context) (*legacy.Block, error) {
+	select {
+	case <-cur.c.BlockWaiter(cur.height):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	b, err := cur.c.GetBlock(ctx, cur.height)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting block %d", cur.height)
+	}
+	cur.height++
+	return b, nil
+}
+
+// OutputDiff returns the IDs of the outputs created and the outputs
+// spent by the blocks after fromHeight up to and including toHeight.
+// Outputs both created and spent within that range appear in
+// neither list, so applying the diff to the state at fromHeight
+// yields the state at toHeight. IDs are listed in the order the
+// blocks create or spend them.
+//
+// The diff is computed by reading every block in the range from the
+// store.
+func (c *Chain) OutputDiff(ctx context.Context, fromHeight, toHeight uint64) (created, spent []bc.Hash, err error) {
+	if toHeight > c.Height() {
+		return nil, nil, errors.WithDetailf(ErrTheDistantFuture, "height %d, blockchain height %d", toHeight, c.Height())
+	}
+	if fromHeight > toHeight {
+		return nil, nil, errors.WithDetailf(ErrBadHeightRange, "from height %d is above to height %d", fromHeight, toHeight)
+	}
+
+	var (
+		isCreated = make(map[bc.Hash]bool)
+		isSpent   = make(map[bc.Hash]bool)
+		order     []bc.Hash
+	)
+	for h := fromHeight + 1; h <= toHeight; h++ {
+		b, err := c.store.GetBlock(ctx, h)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "getting block %d", h)
+		}
+		for _, tx := range b.Transactions {
+			for _, id := range tx.SpentOutputIDs {
+				if isCreated[id] {
+					delete(isCreated, id)
+					continue
+				}
+				isSpent[id] = true
+				order = append(order, id)
+			}
+			for _, id := range tx.ResultIds {
+				if _, ok := tx.Entries[*id].(*bc.Output); !ok {
+					continue // retirements are not outputs
+				}
+				isCreated[*id] = true
+				order = append(order, *id)
+			}
+		}
+	}
+	for _, id := range order {
+		if isCreated[id] {
+			created = append(created, id)
+		} else if isSpent[id] {
+			spent = append(spent, id)
+		}
+	}
+	return created, spent, nil
+}
+
+// BlockSupplyDelta returns the total amount of each asset issued
+// and retired by the transactions in block b. Assets with no
+// issuances or no retirements in b are absent from the respective
+// map. It returns ErrSupplyOverflow if a total overflows a uint64.
+func BlockSupplyDelta(b *legacy.Block) (issued, retired map[bc.AssetID]uint64, err error) {
+	issued = make(map[bc.AssetID]uint64)
+	retired = make(map[bc.AssetID]uint64)
+	add := func(m map[bc.AssetID]uint64, v *bc.AssetAmount) error {
+		sum, ok := checked.AddUint64(m[*v.AssetId], v.Amount)
+		if !ok {
+			return errors.WithDetailf(ErrSupplyOverflow, "total of asset %x in block %d", v.AssetId.Bytes(), b.Height)
+		}
+		m[*v.AssetId] = sum
+		return nil
+	}
+	for _, tx := range b.Transactions {
+		for _, id := range tx.InputIDs {
+			iss, err := tx.Issuance(id)
+			if err != nil {
+				continue // not an issuance
+			}
+			err = add(issued, iss.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		for _, id := range tx.ResultIds {
+			r, ok := tx.Entries[*id].(*bc.Retirement)
+			if !ok {
+				continue
+			}
+			err = add(retired, r.Source.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return issued, retired, nil
+}
+
 // GenerateBlock generates a valid, but unsigned, candidate block from
 // the current pending transaction pool. It returns the new block and
 // a snapshot of what the state snapshot is if the block is applied.
@@ -77,7 +218,7 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot
 	var txEntries []*bc.Tx
 
 	for _, tx := range txs {
-		if len(b.Transactions) >= maxBlockTxs {
+		if len(b.Transactions) >= MaxBlockTxs {
 			break
 		}
 
@@ -152,6 +293,33 @@ func (c *Chain) ApplyValidBlock(block *legacy.Block) (*state.Snapshot, error) {
 	return newSnapshot, nil
 }
 
+// DryRunBlock validates block against the current blockchain state
+// and applies it to a copy of the state snapshot, returning the
+// resulting state root. Neither the Chain nor its store is modified.
+//
+// If the computed root disagrees with the block's AssetsMerkleRoot,
+// DryRunBlock returns the computed root along with ErrBadStateRoot.
+func (c *Chain) DryRunBlock(ctx context.Context, block *legacy.Block) (bc.Hash, error) {
+	prev, snapshot := c.State()
+	if snapshot == nil {
+		return bc.Hash{}, ErrStaleState
+	}
+	err := c.ValidateBlock(block, prev)
+	if err != nil {
+		return bc.Hash{}, err
+	}
+	newSnapshot := state.Copy(snapshot)
+	err = newSnapshot.ApplyBlock(legacy.MapBlock(block))
+	if err != nil {
+		return bc.Hash{}, errors.Sub(ErrBadBlock, err)
+	}
+	root := newSnapshot.Tree.RootHash()
+	if block.AssetsMerkleRoot != root {
+		return root, errors.WithDetailf(ErrBadStateRoot, "computed %x, block has %x", root.Bytes(), block.AssetsMerkleRoot.Bytes())
+	}
+	return root, nil
+}
+
 // CommitBlock commits a block to the blockchain. The block
 // must already have been applied with ApplyValidBlock or
 // ApplyNewBlock, which will have produced the new snapshot that's
@@ -241,6 +409,14 @@ func (c *Chain) ValidateBlockForSig(ctx context.Context, block *legacy.Block) er
 	return errors.Sub(ErrBadBlock, err)
 }
 
+// NewInitialBlock returns the initial block of a new blockchain: a
+// block at height 1 with no transactions and no previous block,
+// whose consensus program requires nSigs of the given pubkeys to
+// sign the next block.
+//
+// To start a new blockchain, pass the initial block's hash to
+// NewChain as the initial block hash, then commit the block with
+// CommitAppliedBlock and an empty state snapshot.
 func NewInitialBlock(pubkeys []ed25519.PublicKey, nSigs int, timestamp time.Time) (*legacy.Block, error) {
 	// TODO(kr): move this into a lower-level package (e.g. chain/protocol/bc)
 	// so that other packages (e.g. chain/protocol/validation) unit tests can
@@ -3,13 +3,16 @@ package protocol
 import (
 	"context"
 	"encoding/hex"
+	"math"
 	"testing"
 	"time"
 
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest/memstore"
 	"chain/protocol/state"
+	"chain/protocol/vm"
 	"chain/testutil"
 )
 
@@ -124,6 +127,135 @@ func TestWaitForBlockSoonTimesout(t *testing.T) {
 	}
 }
 
+func TestBlocksFrom(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestChain(t, time.Now())
+	makeEmptyBlock(t, c) // height=2
+	makeEmptyBlock(t, c) // height=3
+
+	cur, err := c.BlocksFrom(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := uint64(2); want <= 3; want++ {
+		b, err := cur.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Height != want {
+			t.Errorf("got block at height %d want %d", b.Height, want)
+		}
+	}
+
+	// At the tip, Next waits until the context times out.
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = cur.Next(tctx)
+	if err != tctx.Err() {
+		t.Fatalf("Next at tip = %v want %v", err, tctx.Err())
+	}
+
+	// Once a new block lands, the cursor resumes.
+	makeEmptyBlock(t, c) // height=4
+	b, err := cur.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Height != 4 {
+		t.Errorf("got block at height %d want 4", b.Height)
+	}
+}
+
+func TestOutputDiff(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestChain(t, time.Now())
+
+	issue1 := issueTrue(t, c, 10)
+	issue2 := issueTrue(t, c, 20)
+	makeBlock(t, c, []*legacy.Tx{issue1, issue2}) // height=2
+	spend1 := spendTrue(t, issue1, 0)
+	makeBlock(t, c, []*legacy.Tx{spend1}) // height=3
+	spend2 := spendTrue(t, spend1, 0)
+	makeBlock(t, c, []*legacy.Tx{spend2}) // height=4
+
+	out := func(tx *legacy.Tx) bc.Hash { return *tx.OutputID(0) }
+	cases := []struct {
+		from, to    uint64
+		wantCreated []bc.Hash
+		wantSpent   []bc.Hash
+	}{
+		{1, 2, []bc.Hash{out(issue1), out(issue2)}, nil},
+		{2, 3, []bc.Hash{out(spend1)}, []bc.Hash{out(issue1)}},
+		{2, 4, []bc.Hash{out(spend2)}, []bc.Hash{out(issue1)}},
+		{1, 4, []bc.Hash{out(issue2), out(spend2)}, nil},
+		{4, 4, nil, nil},
+	}
+	for _, test := range cases {
+		created, spent, err := c.OutputDiff(ctx, test.from, test.to)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if !testutil.DeepEqual(created, test.wantCreated) {
+			t.Errorf("OutputDiff(%d, %d) created = %x want %x", test.from, test.to, created, test.wantCreated)
+		}
+		if !testutil.DeepEqual(spent, test.wantSpent) {
+			t.Errorf("OutputDiff(%d, %d) spent = %x want %x", test.from, test.to, spent, test.wantSpent)
+		}
+	}
+
+	_, _, err := c.OutputDiff(ctx, 1, 5)
+	if errors.Root(err) != ErrTheDistantFuture {
+		t.Errorf("OutputDiff(1, 5) err = %v want %v", err, ErrTheDistantFuture)
+	}
+	_, _, err = c.OutputDiff(ctx, 3, 2)
+	if errors.Root(err) != ErrBadHeightRange {
+		t.Errorf("OutputDiff(3, 2) err = %v want %v", err, ErrBadHeightRange)
+	}
+}
+
+func TestBlockSupplyDelta(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	issue1 := issueTrue(t, c, 10)
+	issue2 := issueTrue(t, c, 20)
+	makeBlock(t, c, []*legacy.Tx{issue1})
+	assetID := issue1.Inputs[0].AssetID()
+
+	retire := spendTrue(t, issue1, 0)
+	retire.Outputs = []*legacy.TxOutput{
+		legacy.NewTxOutput(assetID, 4, []byte{byte(vm.OP_FAIL)}, nil),
+		legacy.NewTxOutput(assetID, 6, trueProgram, nil),
+	}
+	*retire = *legacy.NewTx(retire.TxData)
+	b := makeBlock(t, c, []*legacy.Tx{issue2, retire})
+	if len(b.Transactions) != 2 {
+		t.Fatalf("got %d txs in block, want 2", len(b.Transactions))
+	}
+
+	issued, retired, err := BlockSupplyDelta(b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	wantIssued := map[bc.AssetID]uint64{assetID: 20}
+	wantRetired := map[bc.AssetID]uint64{assetID: 4}
+	if !testutil.DeepEqual(issued, wantIssued) {
+		t.Errorf("issued = %v want %v", issued, wantIssued)
+	}
+	if !testutil.DeepEqual(retired, wantRetired) {
+		t.Errorf("retired = %v want %v", retired, wantRetired)
+	}
+
+	// Issuances whose total overflows need not form a valid block.
+	b = &legacy.Block{Transactions: []*legacy.Tx{
+		issueTrue(t, c, math.MaxInt64),
+		issueTrue(t, c, math.MaxInt64),
+		issueTrue(t, c, 2),
+	}}
+	_, _, err = BlockSupplyDelta(b)
+	if errors.Root(err) != ErrSupplyOverflow {
+		t.Errorf("overflowing issuances: got error %v, want %v", err, ErrSupplyOverflow)
+	}
+}
+
 func TestGenerateBlock(t *testing.T) {
 	ctx := context.Background()
 	now := time.Unix(233400000, 0)
@@ -193,6 +325,41 @@ func TestGenerateBlock(t *testing.T) {
 	}
 }
 
+func TestDryRunBlock(t *testing.T) {
+	ctx := context.Background()
+	c, b1 := newTestChain(t, time.Now().Add(-time.Minute))
+	_, snapshot := c.State()
+
+	block, newSnapshot, err := c.GenerateBlock(ctx, b1, snapshot, time.Now(), []*legacy.Tx{issueTrue(t, c, 10)})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(block.Transactions) != 1 {
+		t.Fatalf("got %d txs in block, want 1", len(block.Transactions))
+	}
+	want := newSnapshot.Tree.RootHash()
+
+	got, err := c.DryRunBlock(ctx, block)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got != want {
+		t.Errorf("DryRunBlock = %x want %x", got.Bytes(), want.Bytes())
+	}
+	if c.Height() != 1 {
+		t.Errorf("DryRunBlock changed blockchain height to %d", c.Height())
+	}
+
+	block.AssetsMerkleRoot = bc.Hash{}
+	got, err = c.DryRunBlock(ctx, block)
+	if errors.Root(err) != ErrBadStateRoot {
+		t.Errorf("tampered block: got error %v, want %v", err, ErrBadStateRoot)
+	}
+	if got != want {
+		t.Errorf("tampered block: DryRunBlock = %x want %x", got.Bytes(), want.Bytes())
+	}
+}
+
 func TestValidateBlockForSig(t *testing.T) {
 	initialBlock, err := NewInitialBlock(testutil.TestPubs, 1, time.Now())
 	if err != nil {
@@ -260,6 +427,24 @@ func makeEmptyBlock(tb testing.TB, c *Chain) {
 	}
 }
 
+// makeBlock generates a block containing txs on top of c's current
+// state, commits it, and returns it. Transactions that fail
+// validation are silently omitted, as in GenerateBlock.
+func makeBlock(tb testing.TB, c *Chain, txs []*legacy.Tx) *legacy.Block {
+	ctx := context.Background()
+
+	curBlock, curState := c.State()
+	nextBlock, nextState, err := c.GenerateBlock(ctx, curBlock, curState, time.Now(), txs)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	err = c.CommitAppliedBlock(ctx, nextBlock, nextState)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	return nextBlock
+}
+
 func mustDecodeHex(s string) []byte {
 	data, err := hex.DecodeString(s)
 	if err != nil {
@@ -0,0 +1,107 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+)
+
+// Fork returns a scratch Chain that shares c's blocks up to c's
+// current state but keeps everything committed to it in memory.
+// Blocks generated and committed on the fork never reach c's store,
+// and c is unaffected by them. It is intended for simulations that
+// explore hypothetical sequences of blocks and transactions.
+//
+// The fork starts from the most recent state returned by State, so
+// c must be the leader. Its background work ends when ctx is done.
+func (c *Chain) Fork(ctx context.Context) (*Chain, error) {
+	b, snapshot := c.State()
+	if b == nil || snapshot == nil {
+		return nil, ErrStaleState
+	}
+
+	store := &forkStore{
+		parent:         c.store,
+		height:         b.Height,
+		blocks:         make(map[uint64]*legacy.Block),
+		snapshot:       state.Copy(snapshot),
+		snapshotHeight: b.Height,
+	}
+	fork, err := NewChain(ctx, c.InitialBlockHash, store, nil)
+	if err != nil {
+		return nil, err
+	}
+	fork.MaxIssuanceWindow = c.MaxIssuanceWindow
+	fork.AllowedOpcodes = c.AllowedOpcodes
+	fork.DeprecatedOpcodes = c.DeprecatedOpcodes
+	fork.Denominations = c.Denominations
+	fork.MaxWitnessSize = c.MaxWitnessSize
+	fork.MaxTxRunCost = c.MaxTxRunCost
+	fork.setState(b, state.Copy(snapshot))
+	return fork, nil
+}
+
+// forkStore is a Store that reads the blocks of a parent store
+// up to a fixed height and keeps all later blocks and snapshots
+// in memory.
+type forkStore struct {
+	parent Store
+	height uint64 // parent height at the time of the fork
+
+	mu             sync.Mutex // protects the following
+	blocks         map[uint64]*legacy.Block
+	snapshot       *state.Snapshot
+	snapshotHeight uint64
+}
+
+func (s *forkStore) Height(context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.height + uint64(len(s.blocks)), nil
+}
+
+func (s *forkStore) GetBlock(ctx context.Context, height uint64) (*legacy.Block, error) {
+	if height <= s.height {
+		return s.parent.GetBlock(ctx, height)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blocks[height]
+	if !ok {
+		return nil, fmt.Errorf("fork: no block at height %d", height)
+	}
+	return b, nil
+}
+
+func (s *forkStore) LatestSnapshot(context.Context) (*state.Snapshot, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return state.Copy(s.snapshot), s.snapshotHeight, nil
+}
+
+func (s *forkStore) SaveBlock(ctx context.Context, b *legacy.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b.Height <= s.height {
+		return fmt.Errorf("fork: block height %d is not above fork height %d", b.Height, s.height)
+	}
+	existing, ok := s.blocks[b.Height]
+	if ok && existing.Hash() != b.Hash() {
+		return fmt.Errorf("fork: already have a block at height %d", b.Height)
+	}
+	s.blocks[b.Height] = b
+	return nil
+}
+
+func (s *forkStore) FinalizeBlock(context.Context, uint64) error { return nil }
+
+func (s *forkStore) SaveSnapshot(ctx context.Context, height uint64, snapshot *state.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = state.Copy(snapshot)
+	s.snapshotHeight = height
+	return nil
+}
@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+func TestFork(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestChain(t, time.Now())
+	issue := issueTrue(t, c, 10)
+	makeBlock(t, c, []*legacy.Tx{issue}) // height=2
+	_, parentState := c.State()
+	parentRoot := parentState.Tree.RootHash()
+
+	fork, err := c.Fork(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	spend := spendTrue(t, issue, 0)
+	makeBlock(t, fork, []*legacy.Tx{spend}) // fork height=3
+	makeBlock(t, fork, nil)                 // fork height=4
+
+	if fork.Height() != 4 {
+		t.Errorf("fork height = %d want 4", fork.Height())
+	}
+	b, err := fork.GetBlock(ctx, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(b.Transactions) != 1 || b.Transactions[0].ID != issue.ID {
+		t.Errorf("fork block 2 does not contain the parent's issuance")
+	}
+	_, forkState := fork.State()
+	if forkState.Tree.Contains(issue.OutputID(0).Bytes()) {
+		t.Error("fork state still contains spent output")
+	}
+
+	if c.Height() != 2 {
+		t.Errorf("parent height = %d want 2", c.Height())
+	}
+	_, err = c.GetBlock(ctx, 3)
+	if err == nil {
+		t.Error("parent store has block 3 committed on fork")
+	}
+	_, parentState = c.State()
+	if got := parentState.Tree.RootHash(); got != parentRoot {
+		t.Errorf("parent state root = %x want %x", got.Bytes(), parentRoot.Bytes())
+	}
+	if !parentState.Tree.Contains(issue.OutputID(0).Bytes()) {
+		t.Error("parent state lost output spent on fork")
+	}
+
+	// The parent can still commit its own block at height 3.
+	makeBlock(t, c, []*legacy.Tx{spend})
+	if c.Height() != 3 {
+		t.Errorf("parent height = %d want 3", c.Height())
+	}
+}
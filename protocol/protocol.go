@@ -52,6 +52,7 @@ import (
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
+	"chain/protocol/vm"
 )
 
 // maxCachedValidatedTxs is the max number of validated txs to cache.
@@ -88,6 +89,41 @@ type Chain struct {
 	InitialBlockHash  bc.Hash
 	MaxIssuanceWindow time.Duration // only used by generators
 
+	// AllowedOpcodes, if non-nil, restricts the opcodes that may
+	// appear in the programs a transaction's inputs must satisfy.
+	// Data-pushing opcodes are always allowed. Only those top-level
+	// programs are scanned: a program pushed as data, by them or in
+	// a witness argument, and run with CHECKPREDICATE is not, since
+	// such a program can be assembled at run time. So the allowlist
+	// limits what a network's control and issuance programs look
+	// like; it does not sandbox the VM. Only used by generators.
+	AllowedOpcodes map[vm.Op]bool
+
+	// DeprecatedOpcodes maps an opcode to the block height from
+	// which it may no longer appear in the programs a transaction's
+	// inputs must satisfy. A transaction is checked against the
+	// height of the next block. Only used by generators.
+	DeprecatedOpcodes map[vm.Op]uint64
+
+	// Denominations maps an asset ID to the unit in which that
+	// asset must be transferred: every output of the asset must
+	// have an amount that is a multiple of it. Only used by
+	// generators.
+	Denominations map[bc.AssetID]uint64
+
+	// MaxWitnessSize, if positive, limits the total size in bytes
+	// of the witness arguments of a transaction's inputs. Only used
+	// by generators.
+	MaxWitnessSize int
+
+	// MaxTxRunCost, if positive, limits how much of the VM run limit
+	// the programs a transaction's inputs and nonces must satisfy
+	// may consume in total. Each program is already bounded by the
+	// VM's own run limit; this bounds their sum, which witness size
+	// does not, since a program may need no arguments. Only used by
+	// generators.
+	MaxTxRunCost int64
+
 	state struct {
 		cond     sync.Cond // protects height, block, snapshot
 		height   uint64
@@ -68,3 +68,42 @@ func (c *Chain) Recover(ctx context.Context) (*legacy.Block, *state.Snapshot, er
 	}
 	return b, snapshot, nil
 }
+
+// snapshotAt reconstructs the state snapshot as of the given height
+// by replaying blocks onto the latest snapshot saved in the store,
+// or from the initial block if that snapshot is above height. It
+// requires that the store retain every block it replays, and its
+// cost grows with the number of them. The returned snapshot is
+// independent of the Chain's current state.
+func (c *Chain) snapshotAt(ctx context.Context, height uint64) (*state.Snapshot, *legacy.Block, error) {
+	if height > c.Height() {
+		return nil, nil, errors.WithDetailf(ErrTheDistantFuture, "height %d, blockchain height %d", height, c.Height())
+	}
+
+	snapshot, snapshotHeight, err := c.store.LatestSnapshot(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "getting latest snapshot")
+	}
+	if snapshot == nil || snapshotHeight > height {
+		snapshot, snapshotHeight = state.Empty(), 0
+	}
+
+	var b *legacy.Block
+	if snapshotHeight > 0 {
+		b, err = c.store.GetBlock(ctx, snapshotHeight)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "getting snapshot block")
+		}
+	}
+	for h := snapshotHeight + 1; h <= height; h++ {
+		b, err = c.store.GetBlock(ctx, h)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "getting block %d", h)
+		}
+		err = snapshot.ApplyBlock(legacy.MapBlock(b))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "applying block %d", h)
+		}
+	}
+	return snapshot, b, nil
+}
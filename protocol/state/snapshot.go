@@ -69,14 +69,20 @@ func (s *Snapshot) ApplyBlock(block *bc.Block) error {
 	return nil
 }
 
-// ApplyTx updates s in place.
+// ApplyTx updates s in place. If tx's nonces conflict with the
+// nonce set, its prevouts are not all unspent, or its outputs cannot
+// be added to the state tree, it returns an error without modifying s.
 func (s *Snapshot) ApplyTx(tx *bc.Tx) error {
+	// Check nonces and prevouts before changing anything.
+	expiries := make(map[bc.Hash]uint64, len(tx.NonceIDs))
 	for _, n := range tx.NonceIDs {
-		// Add new nonces. They must not conflict with nonces already
-		// present.
+		// New nonces must not conflict with nonces already present.
 		if _, ok := s.Nonces[n]; ok {
 			return fmt.Errorf("conflicting nonce %x", n.Bytes())
 		}
+		if _, ok := expiries[n]; ok {
+			return fmt.Errorf("conflicting nonce %x", n.Bytes())
+		}
 
 		nonce, err := tx.Nonce(n)
 		if err != nil {
@@ -86,19 +92,24 @@ func (s *Snapshot) ApplyTx(tx *bc.Tx) error {
 		if err != nil {
 			return errors.Wrap(err, "applying nonce")
 		}
-
-		s.Nonces[n] = tr.MaxTimeMs
+		expiries[n] = tr.MaxTimeMs
 	}
-
-	// Remove spent outputs. Each output must be present.
+	spent := make(map[bc.Hash]bool, len(tx.SpentOutputIDs))
 	for _, prevout := range tx.SpentOutputIDs {
-		if !s.Tree.Contains(prevout.Bytes()) {
+		// Each spent output must be present, and spent only once.
+		if spent[prevout] || !s.Tree.Contains(prevout.Bytes()) {
 			return fmt.Errorf("invalid prevout %x", prevout.Bytes())
 		}
-		s.Tree.Delete(prevout.Bytes())
+		spent[prevout] = true
 	}
 
-	// Add new outputs. They must not yet be present.
+	// Remove spent outputs and add new ones on a copy of the tree,
+	// which takes constant time, so that a failed insert leaves s.Tree
+	// as it was.
+	tree := *s.Tree
+	for _, prevout := range tx.SpentOutputIDs {
+		tree.Delete(prevout.Bytes())
+	}
 	for _, id := range tx.TxHeader.ResultIds {
 		// Ensure that this result is an output. It could be a retirement
 		// which should not be inserted into the state tree.
@@ -107,10 +118,15 @@ func (s *Snapshot) ApplyTx(tx *bc.Tx) error {
 			continue
 		}
 
-		err := s.Tree.Insert(id.Bytes())
+		err := tree.Insert(id.Bytes())
 		if err != nil {
 			return err
 		}
 	}
+
+	*s.Tree = tree
+	for n, expiryMS := range expiries {
+		s.Nonces[n] = expiryMS
+	}
 	return nil
 }
@@ -64,6 +64,75 @@ func TestApplyIssuanceTwice(t *testing.T) {
 	}
 }
 
+func TestApplyTxFailureLeavesSnapshot(t *testing.T) {
+	// An issuance that also spends an output not in the snapshot.
+	issuance := bctest.NewIssuanceTx(t, bc.EmptyStringHash)
+	assetID := bc.AssetID{}
+	txdata := issuance.TxData
+	txdata.Inputs = append(txdata.Inputs, legacy.NewSpendInput(nil, bc.NewHash([32]byte{1}), assetID, 100, 0, nil, bc.Hash{}, nil))
+	tx := legacy.MapTx(&txdata)
+
+	snap := Empty()
+	err := snap.ApplyTx(tx)
+	if err == nil {
+		t.Fatal("expected error applying tx with missing prevout, got nil")
+	}
+	if len(snap.Nonces) != 0 {
+		t.Errorf("failed ApplyTx added %d nonces", len(snap.Nonces))
+	}
+
+	// The nonce is still available to a valid tx.
+	err = snap.ApplyTx(legacy.MapTx(&issuance.TxData))
+	if err != nil {
+		t.Errorf("applying issuance after failed tx with the same nonce: %s", err)
+	}
+}
+
+func TestApplyTxInsertFailureLeavesSnapshot(t *testing.T) {
+	assetID := bc.AssetID{}
+	sourceID := bc.NewHash([32]byte{0x01, 0x02, 0x03})
+	sc := legacy.SpendCommitment{
+		AssetAmount:    bc.AssetAmount{AssetId: &assetID, Amount: 100},
+		SourceID:       sourceID,
+		SourcePosition: 0,
+		VMVersion:      1,
+		ControlProgram: nil,
+		RefDataHash:    bc.Hash{},
+	}
+	spentOutputID, err := legacy.ComputeOutputID(&sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := legacy.MapTx(&legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, sourceID, assetID, 100, 0, nil, bc.Hash{}, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 100, nil, nil),
+		},
+	})
+
+	// A prefix of the new output's ID makes its insert fail
+	// after the prevout has been checked.
+	snap := Empty()
+	snap.Tree.Insert(spentOutputID.Bytes())
+	snap.Tree.Insert(tx.ResultIds[0].Bytes()[:1])
+	root := snap.Tree.RootHash()
+
+	err = snap.ApplyTx(tx)
+	if err == nil {
+		t.Fatal("expected error inserting output, got nil")
+	}
+	if snap.Tree.RootHash() != root {
+		t.Error("failed ApplyTx changed the state tree")
+	}
+	if !snap.Tree.Contains(spentOutputID.Bytes()) {
+		t.Error("failed ApplyTx removed its prevout")
+	}
+}
+
 func TestCopySnapshot(t *testing.T) {
 	snap := Empty()
 	err := snap.ApplyTx(legacy.MapTx(&bctest.NewIssuanceTx(t, bc.EmptyStringHash).TxData))
@@ -1,13 +1,17 @@
 package protocol
 
 import (
+	"context"
 	"sync"
 
 	"github.com/golang/groupcache/lru"
 
 	"chain/errors"
+	"chain/math/checked"
 	"chain/protocol/bc"
+	"chain/protocol/state"
 	"chain/protocol/validation"
+	"chain/protocol/vm"
 )
 
 // ErrBadTx is returned for transactions failing validation
@@ -17,7 +21,7 @@ var ErrBadTx = errors.New("invalid transaction")
 // per-transaction validation results and is consulted before
 // performing full validation.
 func (c *Chain) ValidateTx(tx *bc.Tx) error {
-	err := c.checkIssuanceWindow(tx)
+	err := c.checkPolicy(tx, c.Height()+1)
 	if err != nil {
 		return err
 	}
@@ -27,7 +31,183 @@ func (c *Chain) ValidateTx(tx *bc.Tx) error {
 		err = validation.ValidateTx(tx, c.InitialBlockHash)
 		c.prevalidated.cache(tx.ID, err)
 	}
-	return errors.Sub(ErrBadTx, err)
+	if err != nil {
+		return errors.Sub(ErrBadTx, err)
+	}
+	return c.checkRunCost(tx)
+}
+
+// checkPolicy applies the generator policy checks configured on c
+// to tx, as a candidate for the block at the given height.
+func (c *Chain) checkPolicy(tx *bc.Tx, height uint64) error {
+	err := c.checkIssuanceWindow(tx)
+	if err != nil {
+		return err
+	}
+	err = c.checkAllowedOpcodes(tx)
+	if err != nil {
+		return err
+	}
+	err = c.checkDeprecatedOpcodes(tx, height)
+	if err != nil {
+		return err
+	}
+	err = c.checkDenominations(tx)
+	if err != nil {
+		return err
+	}
+	return c.checkWitnessSize(tx)
+}
+
+// ValidateTxAtHeight reports whether the given transaction would
+// have been valid against the blockchain state as of the given
+// height: it must be well-formed, within its time range as of that
+// block's timestamp, and spend only outputs that were unspent at
+// that height, with nonces unused once those expired by that
+// timestamp are pruned. Policy checks that depend on the block
+// height, such as DeprecatedOpcodes, are applied as for the block
+// after height, not the current tip.
+//
+// The historical state is reconstructed by replaying blocks from
+// the store onto its latest saved snapshot, or onto the empty state
+// if that snapshot is above height. So a height far below the
+// latest snapshot costs a replay of every block up to it; this is
+// intended for audits, not for validating new transactions.
+// Neither the Chain nor its store is modified.
+func (c *Chain) ValidateTxAtHeight(ctx context.Context, tx *bc.Tx, height uint64) error {
+	err := c.checkPolicy(tx, height+1)
+	if err != nil {
+		return err
+	}
+	err = validation.ValidateTx(tx, c.InitialBlockHash)
+	if err != nil {
+		return errors.Sub(ErrBadTx, err)
+	}
+	err = c.checkRunCost(tx)
+	if err != nil {
+		return err
+	}
+
+	snapshot, b, err := c.snapshotAt(ctx, height)
+	if err != nil {
+		return errors.Wrap(err, "reconstructing historical state")
+	}
+	if b != nil {
+		if tx.MinTimeMs > 0 && tx.MinTimeMs > b.TimestampMS {
+			return errors.WithDetailf(ErrBadTx, "transaction not valid until %d, block %d timestamp is %d", tx.MinTimeMs, height, b.TimestampMS)
+		}
+		if tx.MaxTimeMs > 0 && tx.MaxTimeMs < b.TimestampMS {
+			return errors.WithDetailf(ErrBadTx, "transaction expired at %d, block %d timestamp is %d", tx.MaxTimeMs, height, b.TimestampMS)
+		}
+		snapshot.PruneNonces(b.TimestampMS)
+	}
+
+	err = snapshot.ApplyTx(tx)
+	if err != nil {
+		return errors.Sub(ErrBadTx, err)
+	}
+	return nil
+}
+
+// StateRootAfter returns the state merkle root that would result
+// from applying tx to the current blockchain state. It checks only
+// that tx's prevouts and nonces can be applied; it does not
+// otherwise validate tx. The Chain's state is not modified.
+func (c *Chain) StateRootAfter(ctx context.Context, tx *bc.Tx) (bc.Hash, error) {
+	_, snapshot := c.State()
+	if snapshot == nil {
+		return bc.Hash{}, ErrStaleState
+	}
+	newSnapshot := state.Copy(snapshot)
+	err := newSnapshot.ApplyTx(tx)
+	if err != nil {
+		return bc.Hash{}, errors.Sub(ErrBadTx, err)
+	}
+	return newSnapshot.Tree.RootHash(), nil
+}
+
+// ValidateTxs validates a sequence of transactions as they would be
+// applied in a block with the given timestamp, on top of snapshot.
+// As in GenerateBlock, nonces that have expired by the timestamp are
+// pruned first. Each valid transaction's effects are applied to a
+// working copy of snapshot before the next is validated, so later
+// transactions may spend outputs of earlier ones. An invalid
+// transaction has no effect on the ones after it.
+//
+// The returned slice is indexed like txs and holds nil for each
+// valid transaction. Neither snapshot nor the Chain is modified.
+func (c *Chain) ValidateTxs(snapshot *state.Snapshot, txs []*bc.Tx, timestampMS uint64) []error {
+	working := state.Copy(snapshot)
+	working.PruneNonces(timestampMS)
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		err := c.ValidateTx(tx)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		if tx.MinTimeMs > 0 && tx.MinTimeMs > timestampMS {
+			errs[i] = errors.WithDetailf(ErrBadTx, "transaction not valid until %d, block timestamp is %d", tx.MinTimeMs, timestampMS)
+			continue
+		}
+		if tx.MaxTimeMs > 0 && tx.MaxTimeMs < timestampMS {
+			errs[i] = errors.WithDetailf(ErrBadTx, "transaction expired at %d, block timestamp is %d", tx.MaxTimeMs, timestampMS)
+			continue
+		}
+		// ApplyTx leaves working unchanged if it fails.
+		err = working.ApplyTx(tx)
+		if err != nil {
+			errs[i] = errors.Sub(ErrBadTx, err)
+		}
+	}
+	return errs
+}
+
+// TxAssetFlow returns, for each asset, the total amount tx puts in
+// its outputs (created) and the total amount of the outputs it
+// spends (destroyed). Issuances and retirements are not counted
+// themselves: issued value is created only insofar as it reaches an
+// output, and retired value is destroyed only insofar as it comes
+// from a spend. So value that tx both issues and retires appears in
+// neither map. Since each spend's prevout is part of tx, no
+// blockchain state is needed. Tx must be well formed, for instance
+// having passed ValidateTx.
+func TxAssetFlow(tx *bc.Tx) (created, destroyed map[bc.AssetID]uint64, err error) {
+	created = make(map[bc.AssetID]uint64)
+	destroyed = make(map[bc.AssetID]uint64)
+	add := func(m map[bc.AssetID]uint64, v *bc.AssetAmount) error {
+		sum, ok := checked.AddUint64(m[*v.AssetId], v.Amount)
+		if !ok {
+			return errors.WithDetailf(ErrBadTx, "total of asset %x overflows", v.AssetId.Bytes())
+		}
+		m[*v.AssetId] = sum
+		return nil
+	}
+	for i, id := range tx.InputIDs {
+		sp, err := tx.Spend(id)
+		if err != nil {
+			continue // not a spend
+		}
+		prevout, err := tx.Output(*sp.SpentOutputId)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "getting prevout of input %d", i)
+		}
+		err = add(destroyed, prevout.Source.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, id := range tx.ResultIds {
+		out, err := tx.Output(*id)
+		if err != nil {
+			continue // retirements create nothing
+		}
+		err = add(created, out.Source.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return created, destroyed, nil
 }
 
 type prevalidatedTxsCache struct {
@@ -67,3 +247,170 @@ func (c *Chain) checkIssuanceWindow(tx *bc.Tx) error {
 	}
 	return nil
 }
+
+func (c *Chain) checkAllowedOpcodes(tx *bc.Tx) error {
+	if c.AllowedOpcodes == nil {
+		return nil
+	}
+	for i, prog := range inputPrograms(tx) {
+		insts, err := vm.ParseProgram(prog)
+		if err != nil {
+			return errors.Sub(ErrBadTx, err)
+		}
+		for _, inst := range insts {
+			if isPushOp(inst.Op) {
+				continue // data pushes are always allowed
+			}
+			if !c.AllowedOpcodes[inst.Op] {
+				return errors.WithDetailf(ErrBadTx, "input %d uses opcode %s, which is not allowed on this network", i, inst.Op)
+			}
+		}
+	}
+	return nil
+}
+
+// isPushOp reports whether op only pushes data onto the stack.
+// NOPx50, which sits among the push opcodes, is not one of them.
+func isPushOp(op vm.Op) bool {
+	switch {
+	case op <= vm.OP_PUSHDATA4: // OP_0, OP_DATA_1..OP_DATA_75, OP_PUSHDATAn
+		return true
+	case op == vm.OP_1NEGATE:
+		return true
+	case op >= vm.OP_1 && op <= vm.OP_16:
+		return true
+	}
+	return false
+}
+
+func (c *Chain) checkDeprecatedOpcodes(tx *bc.Tx, height uint64) error {
+	if len(c.DeprecatedOpcodes) == 0 {
+		return nil
+	}
+	for i, prog := range inputPrograms(tx) {
+		insts, err := vm.ParseProgram(prog)
+		if err != nil {
+			return errors.Sub(ErrBadTx, err)
+		}
+		for _, inst := range insts {
+			h, ok := c.DeprecatedOpcodes[inst.Op]
+			if ok && height >= h {
+				return errors.WithDetailf(ErrBadTx, "input %d uses opcode %s, which is deprecated from height %d", i, inst.Op, h)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Chain) checkDenominations(tx *bc.Tx) error {
+	if len(c.Denominations) == 0 {
+		return nil
+	}
+	for i, id := range tx.ResultIds {
+		out, err := tx.Output(*id)
+		if err != nil {
+			continue // retirements are not checked
+		}
+		if out.Source == nil || out.Source.Value == nil || out.Source.Value.AssetId == nil {
+			continue // validation reports this
+		}
+		val := out.Source.Value
+		unit := c.Denominations[*val.AssetId]
+		if unit > 0 && val.Amount%unit != 0 {
+			return errors.WithDetailf(ErrBadTx, "output %d amount %d is not a multiple of its asset's denomination %d", i, val.Amount, unit)
+		}
+	}
+	return nil
+}
+
+func (c *Chain) checkWitnessSize(tx *bc.Tx) error {
+	if c.MaxWitnessSize <= 0 {
+		return nil
+	}
+	var n int
+	for _, id := range tx.InputIDs {
+		var args [][]byte
+		switch e := tx.Entries[id].(type) {
+		case *bc.Spend:
+			args = e.WitnessArguments
+		case *bc.Issuance:
+			args = e.WitnessArguments
+		}
+		for _, arg := range args {
+			n += len(arg)
+		}
+	}
+	if n > c.MaxWitnessSize {
+		return errors.WithDetailf(ErrBadTx, "witness arguments total %d bytes, more than the network maximum of %d", n, c.MaxWitnessSize)
+	}
+	return nil
+}
+
+// checkRunCost runs the programs tx's inputs and nonces must
+// satisfy and checks that their total cost is within MaxTxRunCost.
+// Unlike checkPolicy, it needs tx to have passed validation, so that
+// each program's entries are well formed.
+func (c *Chain) checkRunCost(tx *bc.Tx) error {
+	if c.MaxTxRunCost <= 0 {
+		return nil
+	}
+	var total int64
+	for _, id := range append(append([]bc.Hash{}, tx.InputIDs...), tx.NonceIDs...) {
+		var (
+			e    = tx.Entries[id]
+			prog *bc.Program
+			args [][]byte
+		)
+		switch e := e.(type) {
+		case *bc.Spend:
+			prevout, err := tx.Output(*e.SpentOutputId)
+			if err != nil {
+				return errors.Sub(ErrBadTx, err)
+			}
+			prog, args = prevout.ControlProgram, e.WitnessArguments
+		case *bc.Issuance:
+			prog, args = e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments
+		case *bc.Nonce:
+			prog, args = e.Program, e.WitnessArguments
+		default:
+			continue
+		}
+		cost, err := vm.VerifyCost(validation.NewTxVMContext(tx, e, prog, args))
+		if err != nil {
+			return errors.Sub(ErrBadTx, err)
+		}
+		total += cost
+	}
+	if total > c.MaxTxRunCost {
+		return errors.WithDetailf(ErrBadTx, "programs cost %d in total, more than the network maximum of %d", total, c.MaxTxRunCost)
+	}
+	return nil
+}
+
+// inputPrograms returns, for each of tx's inputs, the program the
+// input must satisfy: the control program of a spend's prevout or
+// the issuance program of an issuance. The result is indexed like
+// tx.InputIDs. It is nil for inputs whose entries are missing or
+// malformed; validation reports those.
+func inputPrograms(tx *bc.Tx) [][]byte {
+	progs := make([][]byte, len(tx.InputIDs))
+	for i, id := range tx.InputIDs {
+		switch e := tx.Entries[id].(type) {
+		case *bc.Spend:
+			if e.SpentOutputId == nil {
+				continue
+			}
+			prevout, err := tx.Output(*e.SpentOutputId)
+			if err != nil || prevout.ControlProgram == nil {
+				continue
+			}
+			progs[i] = prevout.ControlProgram.Code
+		case *bc.Issuance:
+			if e.WitnessAssetDefinition == nil || e.WitnessAssetDefinition.IssuanceProgram == nil {
+				continue
+			}
+			progs[i] = e.WitnessAssetDefinition.IssuanceProgram.Code
+		}
+	}
+	return progs
+}
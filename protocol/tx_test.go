@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"testing"
 	"time"
@@ -9,14 +10,404 @@ import (
 	"golang.org/x/crypto/sha3"
 
 	"chain/crypto/ed25519"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
+	"chain/protocol/validation"
 	"chain/protocol/vm"
 	"chain/protocol/vm/vmutil"
 	"chain/testutil"
 )
 
+func TestValidateTxAtHeight(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestChain(t, time.Now())
+
+	issueTx := issueTrue(t, c, 10)
+	makeBlock(t, c, []*legacy.Tx{issueTx}) // height=2
+	spendTx := spendTrue(t, issueTx, 0)
+	makeBlock(t, c, []*legacy.Tx{spendTx}) // height=3
+
+	cases := []struct {
+		height  uint64
+		wantErr error
+	}{
+		{1, ErrBadTx}, // prevout not yet issued
+		{2, nil},
+		{3, ErrBadTx}, // prevout already spent
+		{4, ErrTheDistantFuture},
+	}
+	for _, test := range cases {
+		err := c.ValidateTxAtHeight(ctx, spendTx.Tx, test.height)
+		if errors.Root(err) != test.wantErr {
+			t.Errorf("ValidateTxAtHeight(%d) = %v want %v", test.height, err, test.wantErr)
+		}
+	}
+
+	// The Chain's current state must be unaffected.
+	if err := c.ValidateTxAtHeight(ctx, spendTx.Tx, 2); err != nil {
+		t.Errorf("second ValidateTxAtHeight(2) = %v want nil", err)
+	}
+	if g := c.Height(); g != 3 {
+		t.Errorf("height = %d want 3", g)
+	}
+
+	// A tx whose time range starts after block 2 was not yet valid
+	// then, even though its prevout was unspent.
+	lateData := spendTx.TxData
+	lateData.MinTime = bc.Millis(time.Now().Add(time.Minute))
+	lateTx := legacy.NewTx(lateData)
+	if err := c.ValidateTxAtHeight(ctx, lateTx.Tx, 2); errors.Root(err) != ErrBadTx {
+		t.Errorf("ValidateTxAtHeight(2) of not-yet-valid tx = %v want %v", err, ErrBadTx)
+	}
+
+	// A deprecation that takes effect after height but by the tip
+	// does not apply to the historical check.
+	makeBlock(t, c, nil) // height=4
+	c.DeprecatedOpcodes = map[vm.Op]uint64{vm.OP_TRUE: 4}
+	if err := c.ValidateTxAtHeight(ctx, spendTx.Tx, 2); err != nil {
+		t.Errorf("ValidateTxAtHeight(2) with OP_TRUE deprecated from 4 = %v want nil", err)
+	}
+	if err := c.ValidateTx(spendTx.Tx); errors.Root(err) != ErrBadTx {
+		t.Errorf("ValidateTx with OP_TRUE deprecated from 4 = %v want %v", err, ErrBadTx)
+	}
+}
+
+func TestAllowedOpcodes(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	c.AllowedOpcodes = map[vm.Op]bool{vm.OP_ADD: true, vm.OP_NUMEQUAL: true}
+
+	cases := []struct {
+		prog    string
+		wantErr error
+	}{
+		{"TRUE", nil},
+		{"1 1 ADD 2 NUMEQUAL", nil},
+		{"1 1 SUB 0 NUMEQUAL", ErrBadTx},
+	}
+	for _, test := range cases {
+		prog, err := vm.Assemble(test.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := issueProgram(t, c, prog, 10)
+		err = c.ValidateTx(tx.Tx)
+		if errors.Root(err) != test.wantErr {
+			t.Errorf("ValidateTx(issuance with %q) = %v want %v", test.prog, err, test.wantErr)
+		}
+	}
+
+	// 0x50 sits among the push opcodes but is a NOP expansion, so
+	// it must be allowed explicitly. The program jumps over it,
+	// since the VM itself rejects expansion opcodes it executes.
+	nopProg := []byte{byte(vm.OP_1), byte(vm.OP_JUMPIF), 7, 0, 0, 0, 0x50, byte(vm.OP_TRUE)}
+	c.AllowedOpcodes[vm.OP_JUMPIF] = true
+	err := c.ValidateTx(issueProgram(t, c, nopProg, 10).Tx)
+	if errors.Root(err) != ErrBadTx {
+		t.Errorf("ValidateTx(issuance with %x) = %v want %v", nopProg, err, ErrBadTx)
+	}
+	c.AllowedOpcodes[0x50] = true
+	err = c.ValidateTx(issueProgram(t, c, nopProg, 10).Tx)
+	if err != nil {
+		t.Errorf("ValidateTx(issuance with %x) with 0x50 allowed = %v want nil", nopProg, err)
+	}
+
+	// Only top-level programs are scanned, so a disallowed opcode in
+	// a program pushed as data and run with CHECKPREDICATE gets by.
+	pred, _ := vm.Assemble("1 1 SUB 0 NUMEQUAL")
+	outer, err := vm.Assemble(fmt.Sprintf("0 0x%x 0 CHECKPREDICATE", pred))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AllowedOpcodes[vm.OP_CHECKPREDICATE] = true
+	err = c.ValidateTx(issueProgram(t, c, outer, 10).Tx)
+	if err != nil {
+		t.Errorf("ValidateTx(issuance running SUB through CHECKPREDICATE) = %v want nil", err)
+	}
+
+	c.AllowedOpcodes = nil
+	prog, _ := vm.Assemble("1 1 SUB 0 NUMEQUAL")
+	err = c.ValidateTx(issueProgram(t, c, prog, 10).Tx)
+	if err != nil {
+		t.Errorf("ValidateTx with no opcode restrictions = %v want nil", err)
+	}
+}
+
+func TestDeprecatedOpcodes(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	prog, err := vm.Assemble("1 1 SUB 0 NUMEQUAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		deprecatedAt uint64
+		wantErr      error
+	}{
+		{3, nil},      // next block is 2
+		{2, ErrBadTx}, // deprecated from the next block
+		{1, ErrBadTx},
+	}
+	for _, test := range cases {
+		c.DeprecatedOpcodes = map[vm.Op]uint64{vm.OP_SUB: test.deprecatedAt}
+		err = c.ValidateTx(issueProgram(t, c, prog, 10).Tx)
+		if errors.Root(err) != test.wantErr {
+			t.Errorf("ValidateTx(SUB deprecated at %d) = %v want %v", test.deprecatedAt, err, test.wantErr)
+		}
+	}
+
+	c.DeprecatedOpcodes = map[vm.Op]uint64{vm.OP_CHECKSIG: 1}
+	err = c.ValidateTx(issueProgram(t, c, prog, 10).Tx)
+	if err != nil {
+		t.Errorf("ValidateTx with unused deprecated opcode = %v want nil", err)
+	}
+}
+
+func TestDenominations(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	assetID := issueTrue(t, c, 10).Inputs[0].AssetID()
+
+	cases := []struct {
+		amount  uint64
+		wantErr error
+	}{
+		{100, nil},
+		{200, nil},
+		{150, ErrBadTx},
+		{99, ErrBadTx},
+	}
+	for _, test := range cases {
+		c.Denominations = map[bc.AssetID]uint64{assetID: 100}
+		err := c.ValidateTx(issueTrue(t, c, test.amount).Tx)
+		if errors.Root(err) != test.wantErr {
+			t.Errorf("ValidateTx(issue %d in units of 100) = %v want %v", test.amount, err, test.wantErr)
+		}
+	}
+
+	c.Denominations = map[bc.AssetID]uint64{bc.AssetID{}: 100}
+	err := c.ValidateTx(issueTrue(t, c, 150).Tx)
+	if err != nil {
+		t.Errorf("ValidateTx with other asset's denomination = %v want nil", err)
+	}
+}
+
+func TestMaxWitnessSize(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	c.MaxWitnessSize = 100
+	prog, err := vm.Assemble("DROP DROP TRUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args    [][]byte
+		wantErr error
+	}{
+		{[][]byte{make([]byte, 50), make([]byte, 50)}, nil},
+		{[][]byte{make([]byte, 50), make([]byte, 51)}, ErrBadTx},
+	}
+	for _, test := range cases {
+		tx := issueProgram(t, c, prog, 10)
+		tx.SetInputArguments(0, test.args)
+		err = c.ValidateTx(tx.Tx)
+		if errors.Root(err) != test.wantErr {
+			t.Errorf("ValidateTx(%d witness bytes) = %v want %v", len(test.args[0])+len(test.args[1]), err, test.wantErr)
+		}
+	}
+}
+
+func TestMaxTxRunCost(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	prog, err := vm.Assemble("1 1 ADD 2 NUMEQUAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := issueProgram(t, c, prog, 10)
+	other := issueProgram(t, c, prog, 10)
+	twoData := one.TxData
+	twoData.Inputs = append(append([]*legacy.TxInput{}, one.Inputs...), other.Inputs...)
+	twoData.Outputs = append(append([]*legacy.TxOutput{}, one.Outputs...), other.Outputs...)
+	two := legacy.NewTx(twoData)
+
+	// Every program of two is within the VM's own run limit, but
+	// together they cost more than the one input of one.
+	var oneCost int64
+	for _, id := range append(append([]bc.Hash{}, one.InputIDs...), one.NonceIDs...) {
+		var cost int64
+		switch e := one.Entries[id].(type) {
+		case *bc.Issuance:
+			cost, err = vm.VerifyCost(validation.NewTxVMContext(one.Tx, e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments))
+		case *bc.Nonce:
+			cost, err = vm.VerifyCost(validation.NewTxVMContext(one.Tx, e, e.Program, e.WitnessArguments))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		oneCost += cost
+	}
+	c.MaxTxRunCost = oneCost
+	err = c.ValidateTx(one.Tx)
+	if err != nil {
+		t.Errorf("ValidateTx(one issuance) with MaxTxRunCost %d = %v want nil", oneCost, err)
+	}
+	err = c.ValidateTx(two.Tx)
+	if errors.Root(err) != ErrBadTx {
+		t.Errorf("ValidateTx(two issuances) with MaxTxRunCost %d = %v want %v", oneCost, err, ErrBadTx)
+	}
+
+	c.MaxTxRunCost = 0
+	err = c.ValidateTx(two.Tx)
+	if err != nil {
+		t.Errorf("ValidateTx(two issuances) with no run cost limit = %v want nil", err)
+	}
+}
+
+func TestTxAssetFlow(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	issueTx := issueTrue(t, c, 10)
+	assetID := issueTx.Inputs[0].AssetID()
+	transferTx := spendTrue(t, issueTx, 0)
+	retireTx := spendTrue(t, issueTx, 0)
+	retireTx.Outputs = []*legacy.TxOutput{
+		legacy.NewTxOutput(assetID, 3, []byte{byte(vm.OP_FAIL)}, nil),
+		legacy.NewTxOutput(assetID, 7, trueProgram, nil),
+	}
+	*retireTx = *legacy.NewTx(retireTx.TxData)
+	issueRetireTx := issueTrue(t, c, 10)
+	issueRetireAssetID := issueRetireTx.Inputs[0].AssetID()
+	issueRetireTx.Outputs = []*legacy.TxOutput{
+		legacy.NewTxOutput(issueRetireAssetID, 4, []byte{byte(vm.OP_FAIL)}, nil),
+		legacy.NewTxOutput(issueRetireAssetID, 6, trueProgram, nil),
+	}
+	*issueRetireTx = *legacy.NewTx(issueRetireTx.TxData)
+
+	cases := []struct {
+		desc                       string
+		tx                         *legacy.Tx
+		wantCreated, wantDestroyed map[bc.AssetID]uint64
+	}{
+		{"issuance", issueTx, map[bc.AssetID]uint64{assetID: 10}, map[bc.AssetID]uint64{}},
+		{"transfer", transferTx, map[bc.AssetID]uint64{assetID: 10}, map[bc.AssetID]uint64{assetID: 10}},
+		{"retirement", retireTx, map[bc.AssetID]uint64{assetID: 7}, map[bc.AssetID]uint64{assetID: 10}},
+		{"issuance and retirement", issueRetireTx, map[bc.AssetID]uint64{issueRetireAssetID: 6}, map[bc.AssetID]uint64{}},
+	}
+	for _, test := range cases {
+		created, destroyed, err := TxAssetFlow(test.tx.Tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if !testutil.DeepEqual(created, test.wantCreated) {
+			t.Errorf("%s: created = %v want %v", test.desc, created, test.wantCreated)
+		}
+		if !testutil.DeepEqual(destroyed, test.wantDestroyed) {
+			t.Errorf("%s: destroyed = %v want %v", test.desc, destroyed, test.wantDestroyed)
+		}
+	}
+}
+
+func TestStateRootAfter(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestChain(t, time.Now())
+	issueTx := issueTrue(t, c, 10)
+	b2 := makeBlock(t, c, []*legacy.Tx{issueTx})
+	spendTx := spendTrue(t, issueTx, 0)
+
+	got, err := c.StateRootAfter(ctx, spendTx.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == b2.AssetsMerkleRoot {
+		t.Error("state root after spend equals the current state root")
+	}
+
+	// Computing the root must not apply the spend.
+	_, snapshot := c.State()
+	if root := snapshot.Tree.RootHash(); root != b2.AssetsMerkleRoot {
+		t.Errorf("current state root = %x want %x", root.Bytes(), b2.AssetsMerkleRoot.Bytes())
+	}
+
+	// Actually committing the spend must produce the predicted root.
+	b3 := makeBlock(t, c, []*legacy.Tx{spendTx})
+	if b3.AssetsMerkleRoot != got {
+		t.Errorf("block state root = %x want %x", b3.AssetsMerkleRoot.Bytes(), got.Bytes())
+	}
+
+	// Now the prevout is gone.
+	_, err = c.StateRootAfter(ctx, spendTx.Tx)
+	if errors.Root(err) != ErrBadTx {
+		t.Errorf("StateRootAfter(double spend) = %v want %v", err, ErrBadTx)
+	}
+}
+
+func TestValidateTxs(t *testing.T) {
+	c, _ := newTestChain(t, time.Now())
+	_, snapshot := c.State()
+	rootBefore := snapshot.Tree.RootHash()
+
+	issueTx := issueTrue(t, c, 10)
+	spendTx := spendTrue(t, issueTx, 0)
+	otherIssueTx := issueTrue(t, c, 20)
+
+	txs := []*bc.Tx{
+		spendTx.Tx,      // prevout not yet issued
+		issueTx.Tx,      // ok
+		spendTx.Tx,      // ok, spends the issuance above
+		spendTx.Tx,      // double spend
+		otherIssueTx.Tx, // ok
+	}
+	want := []error{ErrBadTx, nil, nil, ErrBadTx, nil}
+
+	errs := c.ValidateTxs(snapshot, txs, bc.Millis(time.Now()))
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d", len(errs), len(want))
+	}
+	for i, err := range errs {
+		if errors.Root(err) != want[i] {
+			t.Errorf("tx %d: got error %v, want %v", i, err, want[i])
+		}
+	}
+	if snapshot.Tree.RootHash() != rootBefore {
+		t.Error("ValidateTxs modified the snapshot")
+	}
+
+	errs = c.ValidateTxs(snapshot, []*bc.Tx{issueTx.Tx}, bc.Millis(time.Now().Add(2*time.Hour)))
+	if errors.Root(errs[0]) != ErrBadTx {
+		t.Errorf("expired tx: got error %v, want %v", errs[0], ErrBadTx)
+	}
+
+	// A tx that fails after its nonce is applied must not leave the
+	// nonce behind for a later tx using the same one.
+	badData := issueTx.TxData
+	badSpend := spendTrue(t, issueTrue(t, c, 5), 0) // prevout not issued
+	badData.Inputs = append(append([]*legacy.TxInput{}, issueTx.Inputs...), badSpend.Inputs...)
+	badData.Outputs = append(append([]*legacy.TxOutput{}, issueTx.Outputs...), badSpend.Outputs...)
+	badTx := legacy.NewTx(badData)
+	if badTx.NonceIDs[0] != issueTx.NonceIDs[0] {
+		t.Fatal("test txs do not share a nonce")
+	}
+	errs = c.ValidateTxs(snapshot, []*bc.Tx{badTx.Tx, issueTx.Tx}, bc.Millis(time.Now()))
+	if errors.Root(errs[0]) != ErrBadTx {
+		t.Errorf("tx with bad prevout: got error %v, want %v", errs[0], ErrBadTx)
+	}
+	if errs[1] != nil {
+		t.Errorf("tx reusing nonce of invalid tx: got error %v, want nil", errs[1])
+	}
+
+	// Nor may it leave its valid prevout spent.
+	badData = spendTx.TxData
+	badData.Inputs = append(append([]*legacy.TxInput{}, spendTx.Inputs...), badSpend.Inputs...)
+	badData.Outputs = append(append([]*legacy.TxOutput{}, spendTx.Outputs...), badSpend.Outputs...)
+	badTx = legacy.NewTx(badData)
+	errs = c.ValidateTxs(snapshot, []*bc.Tx{issueTx.Tx, badTx.Tx, spendTx.Tx}, bc.Millis(time.Now()))
+	if errors.Root(errs[1]) != ErrBadTx {
+		t.Errorf("spend with bad prevout: got error %v, want %v", errs[1], ErrBadTx)
+	}
+	if errs[2] != nil {
+		t.Errorf("spend of prevout of invalid tx: got error %v, want nil", errs[2])
+	}
+}
+
 func TestBadMaxIssuanceWindow(t *testing.T) {
 	ctx := context.Background()
 	c, b1 := newTestChain(t, time.Now())
@@ -101,3 +492,56 @@ func issue(t testing.TB, asset *testAsset, dest *testDest, amount uint64) (*lega
 
 	return tx, asset, dest
 }
+
+// trueProgram is an issuance or control program
+// satisfied by any arguments.
+var trueProgram = []byte{byte(vm.OP_TRUE)}
+
+// issueTrue returns a transaction issuing amount units of a new
+// asset on c's blockchain to a single output. Both the issuance
+// program and the output's control program are trueProgram, so
+// neither needs signing.
+func issueTrue(tb testing.TB, c *Chain, amount uint64) *legacy.Tx {
+	return issueProgram(tb, c, trueProgram, amount)
+}
+
+// issueProgram is like issueTrue, but uses issuanceProg as the
+// asset's issuance program. The issuance supplies no arguments.
+func issueProgram(tb testing.TB, c *Chain, issuanceProg []byte, amount uint64) *legacy.Tx {
+	var nonce [8]byte
+	_, err := rand.Read(nonce[:])
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	txin := legacy.NewIssuanceInput(nonce[:], amount, nil, c.InitialBlockHash, issuanceProg, nil, nil)
+	return legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs:  []*legacy.TxInput{txin},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(txin.AssetID(), amount, trueProgram, nil),
+		},
+		MinTime: bc.Millis(time.Now().Add(-time.Minute)),
+		MaxTime: bc.Millis(time.Now().Add(time.Hour)),
+	})
+}
+
+// spendTrue returns a transaction spending the output at index of
+// prev (which must be controlled by trueProgram) to a single output
+// also controlled by trueProgram.
+func spendTrue(tb testing.TB, prev *legacy.Tx, index int) *legacy.Tx {
+	out, err := prev.Tx.Output(*prev.OutputID(index))
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	val := out.Source.Value
+	txin := legacy.NewSpendInput(nil, *out.Source.Ref, *val.AssetId, val.Amount, out.Source.Position, out.ControlProgram.Code, *out.Data, nil)
+	return legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs:  []*legacy.TxInput{txin},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*val.AssetId, val.Amount, trueProgram, nil),
+		},
+		MinTime: bc.Millis(time.Now().Add(-time.Minute)),
+		MaxTime: bc.Millis(time.Now().Add(time.Hour)),
+	})
+}
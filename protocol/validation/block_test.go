@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
@@ -48,6 +49,72 @@ func TestValidateBlock2Err(t *testing.T) {
 	}
 }
 
+func TestValidateBlockDoubleSpend(t *testing.T) {
+	b1 := newInitialBlock(t)
+	assetID := bc.ComputeAssetID([]byte{byte(vm.OP_TRUE)}, &b1.ID, 1, &bc.EmptyStringHash)
+	spend := func(outputProg []byte) *legacy.Tx {
+		return legacy.NewTx(legacy.TxData{
+			Version: 1,
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.NewHash([32]byte{1}), assetID, 10, 0, []byte{byte(vm.OP_TRUE)}, bc.EmptyStringHash, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetID, 10, outputProg, nil),
+			},
+		})
+	}
+
+	cases := []struct {
+		txs     []*legacy.Tx
+		wantErr error
+	}{
+		{[]*legacy.Tx{spend([]byte{1})}, nil},
+		{[]*legacy.Tx{spend([]byte{1}), spend([]byte{2})}, errDoubleSpend},
+	}
+	for i, c := range cases {
+		b2 := generateWithTxs(t, b1, c.txs)
+		err := ValidateBlock(b2, b1, b1.ID, dummyValidateTx)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("case %d: ValidateBlock = %v, want %v", i, err, c.wantErr)
+		}
+	}
+}
+
+func TestValidateBlockDuplicateNonce(t *testing.T) {
+	b1 := newInitialBlock(t)
+	issuanceProg := []byte{byte(vm.OP_TRUE)}
+	assetID := bc.ComputeAssetID(issuanceProg, &b1.ID, 1, &bc.EmptyStringHash)
+	issue := func(nonce, refData []byte) *legacy.Tx {
+		return legacy.NewTx(legacy.TxData{
+			Version: 1,
+			MinTime: b1.TimestampMs,
+			MaxTime: b1.TimestampMs + 10000,
+			Inputs: []*legacy.TxInput{
+				legacy.NewIssuanceInput(nonce, 10, nil, b1.ID, issuanceProg, nil, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetID, 10, []byte{byte(vm.OP_TRUE)}, nil),
+			},
+			ReferenceData: refData,
+		})
+	}
+
+	cases := []struct {
+		txs     []*legacy.Tx
+		wantErr error
+	}{
+		{[]*legacy.Tx{issue([]byte{1}, []byte{1}), issue([]byte{2}, []byte{2})}, nil},
+		{[]*legacy.Tx{issue([]byte{1}, []byte{1}), issue([]byte{1}, []byte{2})}, errDuplicateNonce},
+	}
+	for i, c := range cases {
+		b2 := generateWithTxs(t, b1, c.txs)
+		err := ValidateBlock(b2, b1, b1.ID, dummyValidateTx)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("case %d: ValidateBlock = %v, want %v", i, err, c.wantErr)
+		}
+	}
+}
+
 func TestValidateBlockSig2(t *testing.T) {
 	b1 := newInitialBlock(t)
 	b2 := generate(t, b1)
@@ -97,6 +164,10 @@ func newInitialBlock(tb testing.TB) *bc.Block {
 }
 
 func generate(tb testing.TB, prev *bc.Block) *bc.Block {
+	return generateWithTxs(tb, prev, nil)
+}
+
+func generateWithTxs(tb testing.TB, prev *bc.Block, txs []*legacy.Tx) *bc.Block {
 	b := &legacy.Block{
 		BlockHeader: legacy.BlockHeader{
 			Version:           1,
@@ -107,10 +178,15 @@ func generate(tb testing.TB, prev *bc.Block) *bc.Block {
 				ConsensusProgram: prev.NextConsensusProgram,
 			},
 		},
+		Transactions: txs,
 	}
 
+	var txEntries []*bc.Tx
+	for _, tx := range txs {
+		txEntries = append(txEntries, tx.Tx)
+	}
 	var err error
-	b.TransactionsMerkleRoot, err = bc.MerkleRoot(nil)
+	b.TransactionsMerkleRoot, err = bc.MerkleRoot(txEntries)
 	if err != nil {
 		tb.Fatal(err)
 	}
@@ -33,6 +33,8 @@ type validationState struct {
 
 var (
 	errBadTimeRange          = errors.New("bad time range")
+	errDoubleSpend           = errors.New("output spent more than once in block")
+	errDuplicateNonce        = errors.New("nonce used more than once in block")
 	errEmptyResults          = errors.New("transaction has no results")
 	errMismatchedAssetID     = errors.New("mismatched asset id")
 	errMismatchedBlock       = errors.New("mismatched block")
@@ -50,6 +52,7 @@ var (
 	errPosition              = errors.New("invalid source or destination position")
 	errTxVersion             = errors.New("invalid transaction version")
 	errUnbalanced            = errors.New("unbalanced")
+	errUnexpected            = errors.New("unexpected error during validation")
 	errUntimelyTransaction   = errors.New("block timestamp outside transaction time range")
 	errVersionRegression     = errors.New("version regression")
 	errWrongBlockchain       = errors.New("wrong blockchain")
@@ -142,9 +145,11 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 			parity[*dest.Value.AssetId] = diff
 		}
 
-		for assetID, amount := range parity {
-			if amount != 0 {
-				return errors.WithDetailf(errUnbalanced, "asset %x sources - destinations = %d (should be 0)", assetID.Bytes(), amount)
+		// Check balances in source order, not map order, so that the
+		// same asset is reported every time.
+		for _, src := range e.Sources {
+			if amount := parity[*src.Value.AssetId]; amount != 0 {
+				return errors.WithDetailf(errUnbalanced, "asset %x sources - destinations = %d (should be 0)", src.Value.AssetId.Bytes(), amount)
 			}
 		}
 
@@ -469,6 +474,10 @@ func ValidateBlock(b, prev *bc.Block, initialBlockID bc.Hash, validateTx func(*b
 		return errors.Wrap(err, "checking block header")
 	}
 
+	var (
+		spentBy  = make(map[bc.Hash]int) // output ID -> index of spending tx
+		nonceUse = make(map[bc.Hash]int) // nonce ID -> index of tx using it
+	)
 	for i, tx := range b.Transactions {
 		if b.Version == 1 && tx.Version != 1 {
 			return errors.WithDetailf(errTxVersion, "block version %d, transaction version %d", b.Version, tx.Version)
@@ -484,6 +493,19 @@ func ValidateBlock(b, prev *bc.Block, initialBlockID bc.Hash, validateTx func(*b
 		if err != nil {
 			return errors.Wrapf(err, "validity of transaction %d of %d", i, len(b.Transactions))
 		}
+
+		for _, id := range tx.SpentOutputIDs {
+			if j, ok := spentBy[id]; ok {
+				return errors.WithDetailf(errDoubleSpend, "output %x spent by transactions %d and %d", id.Bytes(), j, i)
+			}
+			spentBy[id] = i
+		}
+		for _, id := range tx.NonceIDs {
+			if j, ok := nonceUse[id]; ok {
+				return errors.WithDetailf(errDuplicateNonce, "nonce %x used by transactions %d and %d", id.Bytes(), j, i)
+			}
+			nonceUse[id] = i
+		}
 	}
 
 	txRoot, err := bc.MerkleRoot(b.Transactions)
@@ -515,6 +537,17 @@ func validateBlockAgainstPrev(b, prev *bc.Block) error {
 }
 
 // ValidateTx validates a transaction.
+//
+// It needs no blockchain state. Each spend's prevout is part of the
+// transaction's entry graph, so its control program and value are
+// checked against the spend directly; only whether the prevout is
+// still unspent is left to state.Snapshot.ApplyTx. That makes
+// ValidateTx suitable for offline verification, given just the
+// transaction and the blockchain's initial block ID.
+//
+// A malformed entry graph that makes validation panic, such as one
+// holding a nil entry, is reported as an error rather than crashing
+// the caller.
 func ValidateTx(tx *bc.Tx, initialBlockID bc.Hash) error {
 	vs := &validationState{
 		blockchainID: initialBlockID,
@@ -523,5 +556,60 @@ func ValidateTx(tx *bc.Tx, initialBlockID bc.Hash) error {
 
 		cache: make(map[bc.Hash]error),
 	}
-	return checkValid(vs, tx.TxHeader)
+	return checkValidRecover(vs, tx.TxHeader)
+}
+
+// checkValidRecover is like checkValid, but reports a panic during
+// validation as errUnexpected.
+func checkValidRecover(vs *validationState, e bc.Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rErr, ok := r.(error); ok {
+				err = errors.Sub(errUnexpected, rErr)
+			} else {
+				err = errors.Wrap(errUnexpected, r)
+			}
+		}
+	}()
+	return checkValid(vs, e)
+}
+
+// ValidateTxAll is like ValidateTx but reports every input that
+// fails validation, not just the first, for diagnosing a broken
+// transaction. Checks that depend on all inputs being valid, such
+// as mux balancing, run only if every input passes. The result is
+// empty if tx is valid. Like ValidateTx, it reports a panic caused
+// by a malformed entry graph as an error.
+func ValidateTxAll(tx *bc.Tx, initialBlockID bc.Hash) []error {
+	vs := &validationState{
+		blockchainID: initialBlockID,
+		tx:           tx,
+
+		cache: make(map[bc.Hash]error),
+	}
+
+	var errs []error
+	for i, id := range tx.InputIDs {
+		e, ok := tx.Entries[id]
+		if !ok {
+			errs = append(errs, errors.Wrapf(bc.ErrMissingEntry, "input %d", i))
+			continue
+		}
+		inputVS := *vs
+		inputVS.entryID = id
+		err := checkValidRecover(&inputVS, e)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "checking input %d", i))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	vs.entryID = tx.ID
+	err := checkValidRecover(vs, tx.TxHeader)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
 }
@@ -362,6 +362,62 @@ func TestTxValidation(t *testing.T) {
 	}
 }
 
+func TestValidateTxAll(t *testing.T) {
+	fixture := sample(t, nil)
+	tx := legacy.NewTx(*fixture.tx).Tx
+
+	errs := ValidateTxAll(tx, fixture.initialBlockID)
+	if len(errs) != 0 {
+		t.Fatalf("valid tx: got errors %v, want none", errs)
+	}
+
+	txSpend(t, tx, 1).WitnessArguments[0] = []byte{}
+	txSpend(t, tx, 2).WitnessArguments[0] = []byte{}
+	errs = ValidateTxAll(tx, fixture.initialBlockID)
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	for i, err := range errs {
+		if rootErr(err) != vm.ErrFalseVMResult {
+			t.Errorf("error %d: got %s, want %s", i, err, vm.ErrFalseVMResult)
+		}
+	}
+
+	// With valid inputs, a balance error is reported.
+	fixture = sample(t, nil)
+	tx = legacy.NewTx(*fixture.tx).Tx
+	out := tx.Entries[*tx.ResultIds[0]].(*bc.Output)
+	mux := tx.Entries[*out.Source.Ref].(*bc.Mux)
+	mux.WitnessDestinations[0].Value.Amount++
+	out.Source.Value.Amount++
+	errs = ValidateTxAll(tx, fixture.initialBlockID)
+	if len(errs) != 1 || rootErr(errs[0]) != errUnbalanced {
+		t.Errorf("got errors %v, want one %s", errs, errUnbalanced)
+	}
+}
+
+func TestUnbalancedErrorDeterministic(t *testing.T) {
+	// Move one spend to another asset, leaving two assets unbalanced.
+	fixture := sample(t, nil)
+	tx := legacy.NewTx(*fixture.tx).Tx
+	out := tx.Entries[*tx.ResultIds[0]].(*bc.Output)
+	mux := tx.Entries[*out.Source.Ref].(*bc.Mux)
+	mux.Sources[1].Value.AssetId = newAssetID(255)
+	sp := tx.Entries[*mux.Sources[1].Ref].(*bc.Spend)
+	sp.WitnessDestination.Value.AssetId = newAssetID(255)
+
+	want := fmt.Sprintf("asset %x sources - destinations = -20 (should be 0)", mux.Sources[0].Value.AssetId.Bytes())
+	for i := 0; i < 20; i++ {
+		err := ValidateTx(tx, fixture.initialBlockID)
+		if rootErr(err) != errUnbalanced {
+			t.Fatalf("got %v, want %s", err, errUnbalanced)
+		}
+		if got := errors.Detail(err); got != want {
+			t.Fatalf("run %d: got detail %q, want %q", i, got, want)
+		}
+	}
+}
+
 func TestNoncelessIssuance(t *testing.T) {
 	tx := bctest.NewIssuanceTx(t, bc.EmptyStringHash, func(tx *legacy.Tx) {
 		// Remove the issuance nonce.
@@ -374,6 +430,22 @@ func TestNoncelessIssuance(t *testing.T) {
 	}
 }
 
+func TestValidateTxPanic(t *testing.T) {
+	fixture := sample(t, nil)
+	tx := legacy.NewTx(*fixture.tx).Tx
+	tx.Entries[*tx.ResultIds[0]] = (*bc.Output)(nil)
+
+	err := ValidateTx(tx, fixture.initialBlockID)
+	if rootErr(err) != errUnexpected {
+		t.Errorf("ValidateTx: got %v, want %s", err, errUnexpected)
+	}
+
+	errs := ValidateTxAll(tx, fixture.initialBlockID)
+	if len(errs) != 1 || rootErr(errs[0]) != errUnexpected {
+		t.Errorf("ValidateTxAll: got %v, want one %s", errs, errUnexpected)
+	}
+}
+
 func TestBlockHeaderValid(t *testing.T) {
 	base := bc.NewBlockHeader(1, 1, &bc.Hash{}, 1, &bc.Hash{}, &bc.Hash{}, nil)
 	baseBytes, _ := proto.Marshal(base)
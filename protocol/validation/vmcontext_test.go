@@ -1,14 +1,17 @@
 package validation
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"testing"
 
+	"chain/crypto/ed25519"
 	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
+	"chain/protocol/vm/vmutil"
 )
 
 func TestCheckOutput(t *testing.T) {
@@ -103,6 +106,41 @@ func TestCheckOutput(t *testing.T) {
 	}
 }
 
+func TestTxSigHash(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog, err := vmutil.NewBuilder().AddOp(vm.OP_TXSIGHASH).AddData(pub).AddOp(vm.OP_CHECKSIG).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialBlockID := bc.Hash{}
+	txin := legacy.NewIssuanceInput([]byte{1}, 5, nil, initialBlockID, prog, nil, nil)
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs:  []*legacy.TxInput{txin},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(txin.AssetID(), 5, []byte{byte(vm.OP_TRUE)}, nil),
+		},
+		MinTime: 1,
+		MaxTime: 2,
+	})
+
+	entry := tx.Entries[tx.InputIDs[0]]
+	got := tx.SigHash(0)
+	want := NewTxVMContext(tx.Tx, entry, &bc.Program{VmVersion: 1, Code: prog}, nil).TxSigHash()
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("SigHash(0) = %x, TXSIGHASH = %x", got.Bytes(), want)
+	}
+
+	tx.SetInputArguments(0, [][]byte{ed25519.Sign(priv, got.Bytes())})
+	err = ValidateTx(tx.Tx, initialBlockID)
+	if err != nil {
+		t.Errorf("ValidateTx with signature over SigHash(0) = %v want nil", err)
+	}
+}
+
 func mustDecodeHex(h string) []byte {
 	bits, err := hex.DecodeString(h)
 	if err != nil {
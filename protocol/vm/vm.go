@@ -40,7 +40,14 @@ var ErrFalseVMResult = errors.New("false VM result")
 // execution.
 var TraceOut io.Writer
 
-func Verify(context *Context) (err error) {
+func Verify(context *Context) error {
+	_, err := VerifyCost(context)
+	return err
+}
+
+// VerifyCost is like Verify, but also returns how much of the
+// program's run limit it consumed, whether or not it succeeded.
+func VerifyCost(context *Context) (cost int64, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if rErr, ok := r.(error); ok {
@@ -52,7 +59,7 @@ func Verify(context *Context) (err error) {
 	}()
 
 	if context.VMVersion != 1 {
-		return ErrUnsupportedVM
+		return 0, ErrUnsupportedVM
 	}
 
 	vm := &virtualMachine{
@@ -66,7 +73,7 @@ func Verify(context *Context) (err error) {
 	for i, arg := range args {
 		err = vm.push(arg, false)
 		if err != nil {
-			return errors.Wrapf(err, "pushing initial argument %d", i)
+			return initialRunLimit - vm.runLimit, errors.Wrapf(err, "pushing initial argument %d", i)
 		}
 	}
 
@@ -75,7 +82,7 @@ func Verify(context *Context) (err error) {
 		err = ErrFalseVMResult
 	}
 
-	return wrapErr(err, vm, args)
+	return initialRunLimit - vm.runLimit, wrapErr(err, vm, args)
 }
 
 // falseResult returns true iff the stack is empty or the top
@@ -220,6 +227,13 @@ type Error struct {
 	Err  error
 	Prog []byte
 	Args [][]byte
+
+	// PC is the offset in Prog of the instruction that failed, or
+	// len(Prog) if the program ran to completion (for instance,
+	// leaving false on the stack). Op is that instruction's opcode
+	// and is meaningful only when PC < len(Prog).
+	PC uint32
+	Op Op
 }
 
 func (e Error) Error() string {
@@ -240,9 +254,14 @@ func wrapErr(err error, vm *virtualMachine, args [][]byte) error {
 	if err == nil {
 		return nil
 	}
-	return Error{
+	e := Error{
 		Err:  err,
 		Prog: vm.program,
 		Args: args,
+		PC:   vm.pc,
+	}
+	if vm.pc < uint32(len(vm.program)) {
+		e.Op = Op(vm.program[vm.pc])
 	}
+	return e
 }
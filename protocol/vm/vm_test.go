@@ -204,6 +204,69 @@ func TestVerifyTxInput(t *testing.T) {
 	}
 }
 
+func TestVerifyCost(t *testing.T) {
+	short := &Context{
+		VMVersion: 1,
+		Code:      []byte{byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL)},
+		Arguments: [][]byte{{2}, {3}},
+	}
+	long := &Context{
+		VMVersion: 1,
+		Code:      []byte{byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL), byte(OP_VERIFY), byte(OP_1), byte(OP_1), byte(OP_ADD), byte(OP_2), byte(OP_NUMEQUAL)},
+		Arguments: [][]byte{{2}, {3}},
+	}
+	shortCost, err := VerifyCost(short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	longCost, err := VerifyCost(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shortCost <= 0 || longCost <= shortCost {
+		t.Errorf("costs = %d, %d; want positive and increasing with program length", shortCost, longCost)
+	}
+
+	cost, err := VerifyCost(&Context{
+		VMVersion: 1,
+		Code:      []byte{byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL)},
+		Arguments: [][]byte{make([]byte, 50001)},
+	})
+	if errors.Root(err) != ErrRunLimitExceeded || cost != 0 {
+		t.Errorf("VerifyCost(oversized argument) = %d, %v want 0, %v", cost, err, ErrRunLimitExceeded)
+	}
+}
+
+func TestErrorLocation(t *testing.T) {
+	cases := []struct {
+		prog   string
+		wantPC uint32
+		wantOp Op
+	}{
+		{"1 VERIFY 0 VERIFY 1", 3, OP_VERIFY},
+		{"1 DROP DROP", 2, OP_DROP},
+		{"0", 1, 0}, // ran to completion with false on the stack
+	}
+	for _, c := range cases {
+		prog, err := Assemble(c.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Verify(&Context{VMVersion: 1, Code: prog})
+		vmErr, ok := err.(Error)
+		if !ok {
+			t.Errorf("%s: got error %v of type %T, want vm.Error", c.prog, err, err)
+			continue
+		}
+		if vmErr.PC != c.wantPC {
+			t.Errorf("%s: PC = %d want %d", c.prog, vmErr.PC, c.wantPC)
+		}
+		if vmErr.PC < uint32(len(prog)) && vmErr.Op != c.wantOp {
+			t.Errorf("%s: Op = %s want %s", c.prog, vmErr.Op, c.wantOp)
+		}
+	}
+}
+
 func TestVerifyBlockHeader(t *testing.T) {
 	consensusProg := []byte{byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL)}
 	context := &Context{